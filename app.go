@@ -5,11 +5,15 @@ import (
 	"github.com/aquasecurity/bench-common/log"
 	"io/ioutil"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
 
 	"github.com/aquasecurity/bench-common/check"
 	"github.com/aquasecurity/bench-common/outputter"
 	"github.com/aquasecurity/bench-common/util"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func app(cmd *cobra.Command, args []string) {
@@ -32,16 +36,38 @@ func app(cmd *cobra.Command, args []string) {
 
 // Main entry point for benchmark functionality
 func Main(filePath string, constraints []string) {
-	controls, err := getControls(filePath, constraints, substitutionFile)
-	if err != nil {
+	if err := run(filePath, constraints); err != nil {
 		util.ExitWithError(err)
 	}
+}
 
-	summary := runControls(controls, "")
-	err = outputResults(controls, summary)
+func run(filePath string, constraints []string) error {
+	if fleetFile != "" {
+		if hostRoot != "" || hostExec != "" || image != "" || sshDest != "" {
+			return fmt.Errorf("--fleet sets the target of each host, it can't be used with --host-root, --host-exec, --image or --ssh")
+		}
+		return runFleet(filePath, constraints, fleetFile)
+	}
+
+	var sshConfig *check.SSHConfig
+	if sshDest != "" {
+		sshConfig = getSSHConfig(sshDest, sshKeys, sshAgent, knownHosts, sshMaxSessions)
+	}
+	target, err := getTarget(hostRoot, hostExec, image, sshConfig)
 	if err != nil {
-		util.ExitWithError(err)
+		return err
+	}
+	if target != nil {
+		defer target.Close() // nolint: errcheck
 	}
+
+	controls, err := getControls(filePath, constraints, substitutionFile, target)
+	if err != nil {
+		return err
+	}
+
+	summary := runControls(controls, "")
+	return outputResults(controls, summary)
 }
 
 func outputResults(controls *check.Controls, summary check.Summary) error {
@@ -77,29 +103,210 @@ func runControls(controls *check.Controls, checkList string) check.Summary {
 	return summary
 }
 
-func getControls(path string, constraints []string, substitutionFile string) (*check.Controls, error) {
-	data, err := ioutil.ReadFile(path)
+func getControls(path string, constraints []string, substitutionFile string, target *check.Target) (*check.Controls, error) {
+	data, customConfigs, err := readControls(path, substitutionFile)
+	if err != nil {
+		return nil, err
+	}
+	if target != nil {
+		customConfigs = append([]interface{}{target}, customConfigs...)
+	}
+	controls, err := check.NewBench().NewControls(data, constraints, customConfigs...)
 	if err != nil {
 		return nil, err
 	}
+
+	return controls, err
+}
+
+// readControls reads the controls file with its substitutions made, and
+// returns the custom configs to load it with, detecting constraints with
+// --detect.
+func readControls(path string, substitutionFile string) ([]byte, []interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
 	s := string(data)
+	var customConfigs []interface{}
 	if substitutionFile != "" {
 		substitutionData, err := ioutil.ReadFile(substitutionFile)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		substituMap, err := util.GetSubstitutionMap(substitutionData)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		s = util.MakeSubstitutions(s, "", substituMap)
+		// exec audits substitute ${key} in each argument instead
+		customConfigs = append(customConfigs, check.Substitutions(substituMap))
+	}
+	if detect {
+		customConfigs = append(customConfigs, check.Detect{})
 	}
-	controls, err := check.NewControls([]byte(s), constraints)
+	return []byte(s), customConfigs, nil
+}
+
+// fleetConfig is the --fleet file, listing the hosts of a fleet run.
+type fleetConfig struct {
+	// Parallel is the number of hosts audited at once
+	Parallel int `yaml:"parallel"`
+	Hosts    []struct {
+		Name string `yaml:"name"`
+		// SSH is [user@]host[:port] of a remote host
+		SSH      string   `yaml:"ssh"`
+		SSHKeys  []string `yaml:"ssh_keys"`
+		HostRoot string   `yaml:"host_root"`
+		HostExec string   `yaml:"host_exec"`
+	} `yaml:"hosts"`
+}
+
+// getFleet returns the fleet of the --fleet file. The SSH flags apply to
+// every SSH host, unless a host sets its own keys.
+func getFleet(path string) (*check.Fleet, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	var config fleetConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse fleet file %s: %v", path, err)
+	}
+	if len(config.Hosts) == 0 {
+		return nil, fmt.Errorf("fleet file %s has no hosts", path)
+	}
 
-	return controls, err
+	fleet := &check.Fleet{Parallel: config.Parallel}
+	for _, h := range config.Hosts {
+		h := h
+		host := check.FleetHost{Name: h.Name}
+		if host.Name == "" {
+			host.Name = h.SSH
+		}
+		if h.SSH != "" || h.HostRoot != "" || h.HostExec != "" {
+			host.Open = func() (*check.Target, error) {
+				var sshConfig *check.SSHConfig
+				if h.SSH != "" {
+					keys := sshKeys
+					if len(h.SSHKeys) > 0 {
+						keys = h.SSHKeys
+					}
+					sshConfig = getSSHConfig(h.SSH, keys, sshAgent, knownHosts, sshMaxSessions)
+				}
+				return getTarget(h.HostRoot, h.HostExec, "", sshConfig)
+			}
+		}
+		fleet.Hosts = append(fleet.Hosts, host)
+	}
+	return fleet, nil
+}
+
+func runFleet(filePath string, constraints []string, fleetFile string) error {
+	fleet, err := getFleet(fleetFile)
+	if err != nil {
+		return err
+	}
+	data, customConfigs, err := readControls(filePath, substitutionFile)
+	if err != nil {
+		return err
+	}
+
+	report := fleet.Run(check.NewBench(), data, constraints, func(controls *check.Controls) check.Summary {
+		summary := runControls(controls, "")
+		normalizeOutputStruct(controls)
+		return summary
+	}, customConfigs...)
+
+	format := outputter.ConsoleFormat
+	if jsonFmt {
+		format = outputter.JSONFormat
+	}
+	return outputter.OutputFleet(report, &outputter.Config{
+		Console: outputter.Console{
+			NoRemediations:    noRemediations,
+			IncludeTestOutput: includeTestOutput,
+		},
+		Format:   format,
+		Filename: outputFile,
+	})
+}
+
+// getSSHConfig returns the configuration of --ssh [user@]host[:port]. The
+// user defaults to the current user, and the keys to the SSH agent when it is
+// running, or the default keys found in ~/.ssh otherwise.
+func getSSHConfig(dest string, keyFiles []string, useAgent bool, knownHosts []string, maxSessions int) *check.SSHConfig {
+	config := &check.SSHConfig{
+		Address:     dest,
+		KeyFiles:    keyFiles,
+		Agent:       useAgent,
+		KnownHosts:  knownHosts,
+		MaxSessions: maxSessions,
+	}
+	if at := strings.LastIndex(dest, "@"); at >= 0 {
+		config.User, config.Address = dest[:at], dest[at+1:]
+	} else if u, err := user.Current(); err == nil {
+		config.User = u.Username
+	}
+
+	if len(config.KeyFiles) == 0 && !config.Agent {
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			config.Agent = true
+		} else if home, err := os.UserHomeDir(); err == nil {
+			for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+				keyFile := filepath.Join(home, ".ssh", name)
+				if _, err := os.Stat(keyFile); err == nil {
+					config.KeyFiles = append(config.KeyFiles, keyFile)
+				}
+			}
+		}
+	}
+	return config
+}
+
+// getTarget returns the target set by --host-root, --image or --ssh, and
+// --host-exec, or nil to audit the host the bench runs on.
+func getTarget(root, shellExec, image string, sshConfig *check.SSHConfig) (*check.Target, error) {
+	if root == "" && shellExec == "" && image == "" && sshConfig == nil {
+		return nil, nil
+	}
+	if root != "" && image != "" {
+		return nil, fmt.Errorf("--host-root and --image can't be used together")
+	}
+	if image != "" && sshConfig != nil {
+		return nil, fmt.Errorf("--ssh and --image can't be used together")
+	}
+	if image != "" && shellExec == "nsenter" {
+		return nil, fmt.Errorf("--host-exec nsenter can't be used with --image")
+	}
+
+	target := &check.Target{Root: root}
+	var err error
+	switch {
+	case image != "":
+		if target, err = check.OpenImage(image, ""); err != nil {
+			return nil, err
+		}
+	case sshConfig != nil:
+		if target, err = check.DialSSH(*sshConfig); err != nil {
+			return nil, err
+		}
+		target.Root = root
+	}
+	switch shellExec {
+	case "":
+	case "chroot":
+		if target.Root == "" {
+			return nil, fmt.Errorf("--host-exec chroot requires --host-root")
+		}
+		target.ShellWrapper = check.ChrootWrapper(target.Root)
+	case "nsenter":
+		target.ShellWrapper = check.NsenterWrapper(1)
+	default:
+		target.Close() // nolint: errcheck
+		return nil, fmt.Errorf("unknown --host-exec %q, expected chroot or nsenter", shellExec)
+	}
+	return target, nil
 }
 
 func normalizeOutputStruct(controls *check.Controls) {
@@ -115,7 +322,7 @@ func normalizeOutputStruct(controls *check.Controls) {
 		controls.Description = controls.Text
 	}
 	// Normalize group description
-	for _, group := range controls.Groups {
+	check.WalkGroups(controls.Groups, func(group *check.Group, _ []*check.Group) {
 		if group.Description == "" && group.Text != "" {
 			group.Description = group.Text
 		}
@@ -125,5 +332,5 @@ func normalizeOutputStruct(controls *check.Controls) {
 				chk.Description = chk.Text
 			}
 		}
-	}
+	})
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/aquasecurity/bench-common/check"
@@ -47,3 +49,140 @@ func TestOutputResults(t *testing.T) {
 		t.Fatalf("JSON output invalid")
 	}
 }
+
+func TestGetTarget(t *testing.T) {
+	target, err := getTarget("", "", "", nil)
+	if err != nil || target != nil {
+		t.Fatalf("expected no target, got %v, %v", target, err)
+	}
+
+	target, err = getTarget("/host", "", "", nil)
+	if err != nil {
+		t.Fatalf("getTarget failed: %v", err)
+	}
+	if target.Root != "/host" || target.ShellWrapper != nil {
+		t.Errorf("unexpected target %+v", target)
+	}
+
+	target, err = getTarget("/host", "chroot", "", nil)
+	if err != nil {
+		t.Fatalf("getTarget failed: %v", err)
+	}
+	if !reflect.DeepEqual(target.ShellWrapper, []string{"chroot", "/host"}) {
+		t.Errorf("unexpected shell wrapper %v", target.ShellWrapper)
+	}
+
+	for _, shellExec := range []string{"chroot", "sudo"} {
+		if _, err := getTarget("", shellExec, "", nil); err == nil {
+			t.Errorf("expected an error for --host-exec %s", shellExec)
+		}
+	}
+
+	rootfs := t.TempDir()
+	target, err = getTarget("", "", rootfs, nil)
+	if err != nil {
+		t.Fatalf("getTarget failed: %v", err)
+	}
+	if target.Type != check.TargetRootFS || target.Root != rootfs {
+		t.Errorf("unexpected target %+v", target)
+	}
+	if _, err := getTarget("/host", "", rootfs, nil); err == nil {
+		t.Errorf("expected an error for --host-root with --image")
+	}
+	if _, err := getTarget("", "nsenter", rootfs, nil); err == nil {
+		t.Errorf("expected an error for --host-exec nsenter with --image")
+	}
+	if _, err := getTarget("", "", rootfs, &check.SSHConfig{Address: "host"}); err == nil {
+		t.Errorf("expected an error for --ssh with --image")
+	}
+}
+
+func TestGetSSHConfig(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "/run/agent.sock")
+
+	config := getSSHConfig("admin@node-1:2222", nil, false, []string{"/etc/ssh/known_hosts"}, 2)
+	want := &check.SSHConfig{
+		Address:     "node-1:2222",
+		User:        "admin",
+		Agent:       true,
+		KnownHosts:  []string{"/etc/ssh/known_hosts"},
+		MaxSessions: 2,
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("expected %+v, got %+v", want, config)
+	}
+
+	config = getSSHConfig("node-1", []string{"/keys/id_ed25519"}, false, nil, 4)
+	if config.Address != "node-1" || config.Agent || config.User == "" {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
+
+func TestRunFleet(t *testing.T) {
+	dir := t.TempDir()
+	controlsFile := filepath.Join(dir, "controls.yaml")
+	fleetPath := filepath.Join(dir, "fleet.yaml")
+	reportFile := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(controlsFile, []byte(`---
+controls:
+id: 1
+text: "Fleet"
+groups:
+- id: 1.1
+  text: "Shell"
+  checks:
+    - id: 1.1.1
+      text: "Ensure the shell runs"
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fleetPath, []byte(`---
+parallel: 2
+hosts:
+- name: local
+- ssh: bench@127.0.0.1:1
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fleet, err := getFleet(fleetPath)
+	if err != nil {
+		t.Fatalf("getFleet failed: %v", err)
+	}
+	if fleet.Parallel != 2 || len(fleet.Hosts) != 2 || fleet.Hosts[0].Open != nil || fleet.Hosts[1].Name != "bench@127.0.0.1:1" {
+		t.Errorf("unexpected fleet %+v", fleet)
+	}
+
+	fleetFile, outputFile = fleetPath, reportFile
+	defer func() { fleetFile, outputFile = "", "" }()
+	if err := run(controlsFile, nil); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	output, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var report check.FleetReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if report.Pass != 1 || report.HostsTotal != 2 || report.HostsUnreachable != 1 {
+		t.Errorf("unexpected fleet totals %+v %+v", report.Summary, report.FleetSummary)
+	}
+	if report.Hosts[1].Error == "" {
+		t.Errorf("expected an error for the unreachable host")
+	}
+	if report.Checks[0].Description != "Ensure the shell runs" {
+		t.Errorf("unexpected check %+v", report.Checks[0])
+	}
+
+	if _, err := getFleet(controlsFile); err == nil {
+		t.Errorf("expected an error for a fleet file without hosts")
+	}
+}
@@ -19,8 +19,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/aquasecurity/bench-common/auditrules"
 	"github.com/aquasecurity/bench-common/log"
 	"go.uber.org/zap"
+	"io"
+	"net"
 	"os"
 	"regexp"
 	"strconv"
@@ -38,6 +41,17 @@ const (
 	defaultArraySeparator       = ","
 )
 
+// Sources of the values evaluated by a test item, besides the output of the
+// audit.
+const (
+	SourceOutput   = "output"
+	SourceStdout   = "stdout"
+	SourceStderr   = "stderr"
+	SourceExitCode = "exit_code"
+	// SourceDuration is the duration of the audit in milliseconds.
+	SourceDuration = "duration"
+)
+
 type testItem struct {
 	Flag    string
 	Path    string
@@ -45,6 +59,7 @@ type testItem struct {
 	Value   string
 	Set     bool
 	Compare compare
+	Source  string
 }
 
 type compare struct {
@@ -59,16 +74,20 @@ type TestOutput struct {
 	ExpectedResult string
 }
 
-func (t *testItem) execute(s string, isMultipleOutput bool) (result TestOutput, err error) {
+func (t *testItem) execute(s string, isMultipleOutput, jsonLines bool) (result TestOutput, err error) {
 	s = strings.TrimRight(s, " \n")
 
 	// If the test has output that should be evaluated for each row
 	// For example - checking that no container is in privileged mode - docker ps and than checking for each container
-	if isMultipleOutput {
+	if isMultipleOutput && jsonLines && s == "" {
+		// Every row of an empty set of rows passes
+		_, result.ExpectedResult, err = t.evaluate(s, false)
+		result.TestResult = true
+	} else if isMultipleOutput {
 		output := strings.Split(s, "\n")
 
 		for _, op := range output {
-			result.TestResult, result.ExpectedResult, err = t.evaluate(op)
+			result.TestResult, result.ExpectedResult, err = t.evaluate(op, false)
 
 			// If the test failed for the current row, no need to keep testing for this output
 			if !result.TestResult {
@@ -76,7 +95,7 @@ func (t *testItem) execute(s string, isMultipleOutput bool) (result TestOutput,
 			}
 		}
 	} else {
-		result.TestResult, result.ExpectedResult, err = t.evaluate(s)
+		result.TestResult, result.ExpectedResult, err = t.evaluate(s, jsonLines)
 	}
 
 	return result, err
@@ -88,8 +107,39 @@ type Tests struct {
 	BinOp     binOp       `yaml:"bin_op"`
 }
 
+// UsesSources tests if a test item evaluates a source other than the output.
+func (ts *Tests) UsesSources() bool {
+	if ts == nil {
+		return false
+	}
+	for _, t := range ts.TestItems {
+		if t.Source != "" && t.Source != SourceOutput {
+			return true
+		}
+	}
+	return false
+}
+
 // Execute perfoms benchmark tests
 func (ts *Tests) Execute(s, testID string, isMultipleOutput bool) *TestOutput {
+	return ts.ExecuteSources(s, nil, testID, isMultipleOutput)
+}
+
+// ExecuteSources performs benchmark tests, where test items with a source
+// evaluate the value of that source instead of the output s.
+func (ts *Tests) ExecuteSources(s string, sources map[string]string, testID string, isMultipleOutput bool) *TestOutput {
+	return ts.execute(s, sources, testID, isMultipleOutput, false)
+}
+
+// ExecuteJSONLines performs benchmark tests like ExecuteSources, on the output
+// of an audit which emits one JSON document per row. Unless each row is tested
+// on its own, path tests evaluate the rows as a list, however many there are.
+// Tests of each row pass when there are no rows.
+func (ts *Tests) ExecuteJSONLines(s string, sources map[string]string, testID string, isMultipleOutput bool) *TestOutput {
+	return ts.execute(s, sources, testID, isMultipleOutput, true)
+}
+
+func (ts *Tests) execute(s string, sources map[string]string, testID string, isMultipleOutput, jsonLines bool) *TestOutput {
 	finalOutput := &TestOutput{}
 	var result bool
 	var err error
@@ -110,7 +160,17 @@ func (ts *Tests) Execute(s, testID string, isMultipleOutput bool) *TestOutput {
 	}
 
 	for i, t := range ts.TestItems {
-		res[i], err = t.execute(s, isMultipleOutput)
+		input, inputJSONLines := s, jsonLines
+		if t.Source != "" && t.Source != SourceOutput {
+			v, ok := sources[t.Source]
+			if !ok {
+				res[i].ExpectedResult = fmt.Sprintf("'%s' is available", t.Source)
+				logger.Info("Failed running test ", zap.String("testID", testID), zap.String("source", t.Source), zap.Error(errors.New("source is not available")))
+				continue
+			}
+			input, inputJSONLines = v, false
+		}
+		res[i], err = t.execute(input, isMultipleOutput, inputJSONLines)
 		if err != nil {
 			logger.Info("Failed running test ", zap.String("testID", testID), zap.Error(err))
 
@@ -197,7 +257,7 @@ func getFlagValue(s, flag string) string {
 	return flagVal
 }
 
-func (t *testItem) evaluate(output string) (TestResult bool, ExpectedResult string, err error) {
+func (t *testItem) evaluate(output string, jsonLines bool) (TestResult bool, ExpectedResult string, err error) {
 	var match bool
 	var flagVal string
 
@@ -211,7 +271,11 @@ func (t *testItem) evaluate(output string) (TestResult bool, ExpectedResult stri
 		var jsonInterface interface{}
 
 		if t.Path != "" {
-			err := unmarshal(output, &jsonInterface)
+			unmarshalOutput := unmarshal
+			if jsonLines {
+				unmarshalOutput = unmarshalRows
+			}
+			err := unmarshalOutput(output, &jsonInterface)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "failed to load YAML or JSON from provided input \"%s\": %v\n", output, err)
 				return false, "", errors.New("failed to load YAML or JSON")
@@ -326,7 +390,7 @@ func compareOp(tCompareOp, flagVal, tCompareValue, flagName string) (bool, strin
 
 	case "valid_elements":
 		expectedResultPattern = "'%s' contains valid elements from '%s'"
-		s := splitAndRemoveLastSeparator(flagVal, defaultArraySeparator)
+		s := listElements(flagVal)
 		target := splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator)
 		testResult = allElementsValid(s, target)
 
@@ -343,6 +407,14 @@ func compareOp(tCompareOp, flagVal, tCompareValue, flagName string) (bool, strin
 			return false, fmt.Sprintf(expectedResultPattern, flagName, tCompareValue), fmt.Errorf("not numeric value - flag: %q - compareValue: %q %v", flagVal, tCompareValue, err)
 		}
 		testResult = (max & requested) == requested
+
+	case "cidr":
+		expectedResultPattern = "'%s' is an address within '%s'"
+		testResult = addressInCIDRs(flagVal, splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator))
+
+	case "has_audit_rule":
+		expectedResultPattern = "'%s' has audit rules equivalent to '%s'"
+		testResult = hasAuditRules(flagVal, tCompareValue)
 	default:
 		return testResult, expectedResultPattern, nil
 	}
@@ -380,6 +452,63 @@ func allElementsValid(s, t []string) bool {
 	return true
 }
 
+// addressInCIDRs tests if address is an IP address within one of the CIDR ranges.
+func addressInCIDRs(address string, cidrs []string) bool {
+	ip := net.ParseIP(strings.Trim(strings.TrimSpace(address), "[]"))
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// listElements splits a comma separated list into its elements. A JSON list,
+// e.g. from a path test on a native audit type, is split into its items.
+func listElements(s string) []string {
+	var items []string
+	if strings.HasPrefix(strings.TrimSpace(s), "[") && json.Unmarshal([]byte(s), &items) == nil {
+		return items
+	}
+	return splitAndRemoveLastSeparator(s, defaultArraySeparator)
+}
+
+// hasAuditRules tests if the rule set contains a rule equivalent to each of
+// the expected rules. The rule set is either audit rules, one per line, or
+// rules normalized by the audit_rules audit type, one JSON document per line.
+func hasAuditRules(ruleSet, expected string) bool {
+	want, err := auditrules.ParseRules([]byte(expected))
+	if err != nil || len(want) == 0 {
+		return false
+	}
+
+	var rules []*auditrules.Rule
+	for _, line := range strings.Split(ruleSet, "\n") {
+		line = strings.TrimSpace(line)
+		rule := &auditrules.Rule{}
+		if strings.HasPrefix(line, "{") {
+			if json.Unmarshal([]byte(line), rule) != nil {
+				continue
+			}
+		} else if rule, err = auditrules.Parse(line); err != nil {
+			// Lines which are not rules, e.g. comments, are ignored
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	for _, rule := range want {
+		if !auditrules.Contains(rules, rule) {
+			return false
+		}
+	}
+	return true
+}
+
 func splitAndRemoveLastSeparator(s, sep string) []string {
 	cleanS := strings.TrimRight(strings.TrimSpace(s), sep)
 	if len(cleanS) == 0 {
@@ -408,6 +537,35 @@ func unmarshal(s string, jsonInterface *interface{}) error {
 	return nil
 }
 
+// unmarshalRows unmarshals the output of an audit emitting one JSON document
+// per row as a list of the rows.
+func unmarshalRows(s string, jsonInterface *interface{}) error {
+	rows, err := unmarshalJSONLines([]byte(s))
+	if err != nil {
+		return err
+	}
+	*jsonInterface = rows
+	return nil
+}
+
+// unmarshalJSONLines decodes a stream of JSON documents.
+func unmarshalJSONLines(data []byte) ([]interface{}, error) {
+	rows := []interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var row interface{}
+		err := decoder.Decode(&row)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
 func executeJSONPath(path string, jsonInterface interface{}) (string, error) {
 	j := jsonpath.New("jsonpath")
 	j.AllowMissingKeys(true)
@@ -78,6 +78,165 @@ func TestTestExecute(t *testing.T) {
 	}
 }
 
+func TestTestExecuteSources(t *testing.T) {
+	sources := map[string]string{SourceStdout: "enabled\n", SourceStderr: "warning: deprecated\n", SourceExitCode: "0", SourceDuration: "12"}
+	output := "enabled\nwarning: deprecated\n"
+
+	cases := []struct {
+		name    string
+		tests   string
+		sources map[string]string
+		want    bool
+	}{
+		{
+			name: "exit code",
+			tests: `
+test_items:
+- source: exit_code
+  compare:
+    op: eq
+    value: 0
+`,
+			sources: sources,
+			want:    true,
+		},
+		{
+			name: "stdout",
+			tests: `
+test_items:
+- source: stdout
+  compare:
+    op: eq
+    value: enabled
+`,
+			sources: sources,
+			want:    true,
+		},
+		{
+			name: "stderr and duration",
+			tests: `
+test_items:
+- source: stderr
+  compare:
+    op: eq
+    value: ""
+- source: duration
+  compare:
+    op: lt
+    value: 1000
+bin_op: or
+`,
+			sources: sources,
+			want:    true,
+		},
+		{
+			name: "output",
+			tests: `
+test_items:
+- source: output
+  compare:
+    op: has
+    value: deprecated
+`,
+			want: true,
+		},
+		{
+			name: "unavailable source",
+			tests: `
+test_items:
+- source: exit_code
+  compare:
+    op: eq
+    value: 0
+`,
+			want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := new(Tests)
+			if err := yaml.Unmarshal([]byte(c.tests), ts); err != nil {
+				t.Fatalf("error unmarshaling tests yaml: %v", err)
+			}
+			res := ts.ExecuteSources(output, c.sources, c.name, false)
+			if res.TestResult != c.want {
+				t.Errorf("expected:%v, got:%v\n", c.want, res)
+			}
+			if res.ActualResult != output {
+				t.Errorf("expected actual result %q, got %q", output, res.ActualResult)
+			}
+		})
+	}
+}
+
+func TestTestExecuteJSONLines(t *testing.T) {
+	// e.g. docker inspect --format '{{json .}}' of two containers
+	output := `{"Name": "web", "Privileged": false}
+{"Name": "db", "Privileged": true}
+`
+	cases := []struct {
+		name      string
+		output    string
+		path      string
+		jsonLines bool
+		want      string
+	}{
+		// Other output is parsed as YAML, which keeps the first document
+		{name: "yaml", output: output, path: "{.Name}", want: "web"},
+		{name: "json lines", output: output, path: "{[*].Name}", jsonLines: true, want: "web db"},
+		{name: "single row", output: `{"Name": "web"}` + "\n", path: "{[*].Name}", jsonLines: true, want: "web"},
+		{name: "no rows", output: "", path: "{[*].Name}", jsonLines: true, want: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := new(Tests)
+			tests := fmt.Sprintf("test_items:\n- path: %q\n  compare:\n    op: eq\n    value: %q\n", c.path, c.want)
+			if err := yaml.Unmarshal([]byte(tests), ts); err != nil {
+				t.Fatalf("error unmarshaling tests yaml: %v", err)
+			}
+			var res *TestOutput
+			if c.jsonLines {
+				res = ts.ExecuteJSONLines(c.output, nil, c.name, false)
+			} else {
+				res = ts.ExecuteSources(c.output, nil, c.name, false)
+			}
+			if !res.TestResult {
+				t.Errorf("expected %s to be %q, got:%v\n", c.path, c.want, res)
+			}
+		})
+	}
+}
+
+func TestTestExecuteJSONLines_MultipleValues(t *testing.T) {
+	ts := new(Tests)
+	tests := "test_items:\n- path: \"{.Privileged}\"\n  compare:\n    op: eq\n    value: false\n"
+	if err := yaml.Unmarshal([]byte(tests), ts); err != nil {
+		t.Fatalf("error unmarshaling tests yaml: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{name: "rows", output: "{\"Privileged\": false}\n{\"Privileged\": false}\n", want: true},
+		{name: "privileged row", output: "{\"Privileged\": false}\n{\"Privileged\": true}\n", want: false},
+		// No container is privileged when there are none
+		{name: "no rows", output: "", want: true},
+	}
+	for _, c := range cases {
+		res := ts.ExecuteJSONLines(c.output, nil, c.name, true)
+		if res.TestResult != c.want {
+			t.Errorf("%s: expected %v, got:%v", c.name, c.want, res)
+		}
+		if res.ExpectedResult != "'' is equal to 'false'" {
+			t.Errorf("%s: unexpected expected result %q", c.name, res.ExpectedResult)
+		}
+	}
+}
+
 func Test_getFlagValue(t *testing.T) {
 
 	type TestRegex struct {
@@ -686,6 +845,12 @@ func TestCompareOp(t *testing.T) {
 		{label: "op=valid_elements, valid_elements compareValue not contains flagVal", op: "valid_elements", flagVal: "a,b", flagName: "testingFlagAB",
 			compareValue: "c,d,x,y", expectedResultPattern: "'testingFlagAB' contains valid elements from 'c,d,x,y'",
 			testResult: false},
+		{label: "op=valid_elements, valid_elements JSON list flagVal", op: "valid_elements", flagVal: `["a","b"]`, flagName: "testingFlagAB",
+			compareValue: "a,b,c,d", expectedResultPattern: "'testingFlagAB' contains valid elements from 'a,b,c,d'",
+			testResult: true},
+		{label: "op=valid_elements, valid_elements JSON list flagVal not contained", op: "valid_elements", flagVal: `["a","x"]`, flagName: "testingFlagAX",
+			compareValue: "a,b,c,d", expectedResultPattern: "'testingFlagAX' contains valid elements from 'a,b,c,d'",
+			testResult: false},
 
 		// Test Op "bitmask"
 		{label: "op=bitmask, 644 AND 640", op: "bitmask", flagVal: "640", flagName: "testingFlagFile640",
@@ -706,6 +871,37 @@ func TestCompareOp(t *testing.T) {
 		{label: "op=bitmask, 644 AND Nirvana", op: "bitmask", flagVal: "211", flagName: "testingFlagFileWrongPerm",
 			compareValue: "Nirvana", expectedResultPattern: "'testingFlagFileWrongPerm' is testing for a non numeric value: 'Nirvana'",
 			testResult: false},
+
+		// Test Op "cidr"
+		{label: "op=cidr, loopback address", op: "cidr", flagVal: "127.0.0.1", flagName: "address",
+			compareValue: "127.0.0.0/8,::1/128", expectedResultPattern: "'address' is an address within '127.0.0.0/8,::1/128'",
+			testResult: true},
+		{label: "op=cidr, IPv6 loopback address", op: "cidr", flagVal: "::1", flagName: "address",
+			compareValue: "127.0.0.0/8,::1/128", expectedResultPattern: "'address' is an address within '127.0.0.0/8,::1/128'",
+			testResult: true},
+		{label: "op=cidr, any address", op: "cidr", flagVal: "0.0.0.0", flagName: "address",
+			compareValue: "127.0.0.0/8,::1/128", expectedResultPattern: "'address' is an address within '127.0.0.0/8,::1/128'",
+			testResult: false},
+		{label: "op=cidr, not an address", op: "cidr", flagVal: "localhost", flagName: "address",
+			compareValue: "127.0.0.0/8", expectedResultPattern: "'address' is an address within '127.0.0.0/8'",
+			testResult: false},
+
+		// Test Op "has_audit_rule"
+		{label: "op=has_audit_rule, auditctl -l output", op: "has_audit_rule", flagVal: "-a always,exit -F arch=b64 -S adjtimex,settimeofday -F key=time-change\n-w /etc/group -p wa -k identity", flagName: "rules",
+			compareValue: "-a always,exit -F arch=b64 -S settimeofday -S adjtimex -k time-change", expectedResultPattern: "'rules' has audit rules equivalent to '-a always,exit -F arch=b64 -S settimeofday -S adjtimex -k time-change'",
+			testResult: true},
+		{label: "op=has_audit_rule, several rules", op: "has_audit_rule", flagVal: "## comment\n-w /etc/group -p wa -k identity\n-w /etc/passwd -p wa -k identity", flagName: "rules",
+			compareValue: "-w /etc/passwd -p aw -k identity\n-w /etc/group -p wa -k identity", expectedResultPattern: "'rules' has audit rules equivalent to '-w /etc/passwd -p aw -k identity\n-w /etc/group -p wa -k identity'",
+			testResult: true},
+		{label: "op=has_audit_rule, missing rule", op: "has_audit_rule", flagVal: "-w /etc/group -p wa -k identity", flagName: "rules",
+			compareValue: "-w /etc/group -p wa -k identity\n-w /etc/shadow -p wa -k identity", expectedResultPattern: "'rules' has audit rules equivalent to '-w /etc/group -p wa -k identity\n-w /etc/shadow -p wa -k identity'",
+			testResult: false},
+		{label: "op=has_audit_rule, normalized rules", op: "has_audit_rule", flagVal: `{"action":"always","list":"exit","syscalls":[],"fields":[],"path":"/etc/group","permissions":"wa","keys":["identity"],"raw":"-w /etc/group -p wa -k identity"}`, flagName: "rules",
+			compareValue: "-a always,exit -F path=/etc/group -F perm=wa -F key=identity", expectedResultPattern: "'rules' has audit rules equivalent to '-a always,exit -F path=/etc/group -F perm=wa -F key=identity'",
+			testResult: true},
+		{label: "op=has_audit_rule, no rules", op: "has_audit_rule", flagVal: "No rules", flagName: "rules",
+			compareValue: "-e 2", expectedResultPattern: "'rules' has audit rules equivalent to '-e 2'",
+			testResult: false},
 	}
 
 	for _, c := range cases {
@@ -867,6 +1063,13 @@ authentication:
 tlsCipherSuites:
   - TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
   - TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+`,
+			kubeletConfig{},
+			false,
+		},
+		{
+			`{"key": "net.ipv4.ip_forward", "value": "0"}
+{"key": "kernel.randomize_va_space", "value": "2"}
 `,
 			kubeletConfig{},
 			false,
@@ -0,0 +1,286 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auditrules parses Linux audit rules, as written in audit.rules(7)
+// files and listed by `auditctl -l`, into a normalized form, so rules can be
+// compared regardless of the order and spelling of their arguments.
+package auditrules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const allPermissions = "rwxa"
+
+var (
+	fieldRe = regexp.MustCompile(`^([A-Za-z0-9_]+)(!=|<=|>=|&=|=|<|>|&)(.*)$`)
+
+	// unsetValues are the spellings of an unset id, e.g. auid!=unset
+	unsetValues = map[string]bool{"unset": true, "-1": true, "4294967295": true}
+	idFields    = map[string]bool{
+		"auid": true, "uid": true, "euid": true, "suid": true, "fsuid": true, "loginuid": true,
+		"gid": true, "egid": true, "sgid": true, "fsgid": true, "obj_uid": true, "obj_gid": true,
+	}
+	archAliases = map[string]string{
+		"x86_64": "b64", "aarch64": "b64", "ppc64": "b64", "ppc64le": "b64", "s390x": "b64",
+		"i386": "b32", "i486": "b32", "i586": "b32", "i686": "b32", "arm": "b32", "s390": "b32",
+	}
+	actions = map[string]bool{"always": true, "never": true}
+)
+
+// Rule is a normalized audit rule. A watch, e.g. `-w /etc/group -p wa`, is
+// normalized like the equivalent `-a always,exit -F path=/etc/group -F perm=wa`.
+// Lines which configure the audit system, e.g. `-e 2`, are rules with a
+// Control option and its Value.
+type Rule struct {
+	Control     string   `json:"control,omitempty"`
+	Value       string   `json:"value,omitempty"`
+	Action      string   `json:"action,omitempty"`
+	List        string   `json:"list,omitempty"`
+	Arch        string   `json:"arch,omitempty"`
+	Syscalls    []string `json:"syscalls"`
+	Fields      []string `json:"fields"`
+	Path        string   `json:"path,omitempty"`
+	Permissions string   `json:"permissions,omitempty"`
+	Keys        []string `json:"keys"`
+	Raw         string   `json:"raw"`
+}
+
+// Parse parses a single rule.
+func Parse(line string) (*Rule, error) {
+	rule := &Rule{Syscalls: []string{}, Fields: []string{}, Keys: []string{}, Raw: strings.TrimSpace(line)}
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty audit rule")
+	}
+
+	value := func(i int) (string, error) {
+		if i+1 >= len(args) {
+			return "", fmt.Errorf("missing value for %s in audit rule %q", args[i], rule.Raw)
+		}
+		return args[i+1], nil
+	}
+
+	watch := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-a", "-A":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			for _, part := range strings.Split(v, ",") {
+				if actions[part] {
+					rule.Action = part
+				} else {
+					rule.List = part
+				}
+			}
+		case "-w":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			watch = true
+			rule.Action, rule.List, rule.Path = "always", "exit", v
+		case "-p":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			rule.Permissions = v
+		case "-S":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			for _, s := range strings.Split(v, ",") {
+				if s != "" && s != "all" {
+					rule.Syscalls = append(rule.Syscalls, s)
+				}
+			}
+		case "-k":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			rule.Keys = append(rule.Keys, v)
+		case "-F", "-C":
+			v, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			if err := rule.addField(arg, v); err != nil {
+				return nil, err
+			}
+		default:
+			if i != 0 || !strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("unexpected %q in audit rule %q", arg, rule.Raw)
+			}
+			// A control option, e.g. -D, -b 8192 or --backlog_wait_time 60000
+			rule.Control = arg
+			rule.Value = strings.Join(args[1:], " ")
+			return rule, nil
+		}
+	}
+
+	if rule.List == "" {
+		return nil, fmt.Errorf("audit rule %q has no -a or -w", rule.Raw)
+	}
+	if watch && rule.Permissions == "" {
+		rule.Permissions = allPermissions
+	}
+	rule.normalize()
+	return rule, nil
+}
+
+// addField adds a -F or -C field, keeping the fields which have their own
+// attribute apart.
+func (r *Rule) addField(opt, field string) error {
+	m := fieldRe.FindStringSubmatch(field)
+	if m == nil {
+		return fmt.Errorf("invalid field %q in audit rule %q", field, r.Raw)
+	}
+	name, op, value := strings.ToLower(m[1]), m[2], m[3]
+	if opt == "-C" {
+		r.Fields = append(r.Fields, "C:"+name+op+value)
+		return nil
+	}
+
+	switch {
+	case name == "arch" && op == "=":
+		r.Arch = value
+		if alias, ok := archAliases[value]; ok {
+			r.Arch = alias
+		}
+	case name == "key" && op == "=":
+		r.Keys = append(r.Keys, value)
+	case (name == "path" || name == "dir") && op == "=":
+		r.Path = value
+	case name == "perm" && op == "=":
+		r.Permissions = value
+	default:
+		if idFields[name] && unsetValues[value] {
+			value = "unset"
+		}
+		r.Fields = append(r.Fields, name+op+value)
+	}
+	return nil
+}
+
+func (r *Rule) normalize() {
+	r.Syscalls = sortedUnique(r.Syscalls)
+	r.Fields = sortedUnique(r.Fields)
+	r.Keys = sortedUnique(r.Keys)
+	r.Path = strings.TrimSuffix(r.Path, "/")
+
+	var perms strings.Builder
+	for _, p := range allPermissions {
+		if strings.ContainsRune(r.Permissions, p) {
+			perms.WriteRune(p)
+		}
+	}
+	r.Permissions = perms.String()
+}
+
+// Canonical returns the rule in a canonical form. Equivalent rules have the
+// same canonical form.
+func (r *Rule) Canonical() string {
+	if r.Control != "" {
+		return strings.TrimSpace(r.Control + " " + r.Value)
+	}
+	parts := []string{"-a " + r.Action + "," + r.List}
+	if r.Arch != "" {
+		parts = append(parts, "-F arch="+r.Arch)
+	}
+	if len(r.Syscalls) > 0 {
+		parts = append(parts, "-S "+strings.Join(r.Syscalls, ","))
+	}
+	if r.Path != "" {
+		parts = append(parts, "-F path="+r.Path)
+	}
+	if r.Permissions != "" {
+		parts = append(parts, "-F perm="+r.Permissions)
+	}
+	for _, f := range r.Fields {
+		if strings.HasPrefix(f, "C:") {
+			parts = append(parts, "-C "+strings.TrimPrefix(f, "C:"))
+		} else {
+			parts = append(parts, "-F "+f)
+		}
+	}
+	for _, k := range r.Keys {
+		parts = append(parts, "-F key="+k)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Equivalent tests if two rules have the same effect.
+func (r *Rule) Equivalent(other *Rule) bool {
+	return r.Canonical() == other.Canonical()
+}
+
+// ParseRules parses the rules of an audit.rules(7) file or of the output of
+// `auditctl -l`. Blank lines and comments are skipped.
+func ParseRules(data []byte) ([]*Rule, error) {
+	var rules []*Rule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "No rules" {
+			continue
+		}
+		rule, err := Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// Contains tests if one of the rules is equivalent to rule.
+func Contains(rules []*Rule, rule *Rule) bool {
+	for _, r := range rules {
+		if r.Equivalent(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnique(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,144 @@
+package auditrules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line string
+		want Rule
+	}{
+		{
+			line: "-a always,exit -F arch=b64 -S settimeofday -S adjtimex -k time-change",
+			want: Rule{Action: "always", List: "exit", Arch: "b64", Syscalls: []string{"adjtimex", "settimeofday"},
+				Fields: []string{}, Keys: []string{"time-change"}},
+		},
+		{
+			line: "-w /etc/group -p wa -k identity",
+			want: Rule{Action: "always", List: "exit", Syscalls: []string{}, Fields: []string{},
+				Path: "/etc/group", Permissions: "wa", Keys: []string{"identity"}},
+		},
+		{
+			line: "-w /etc/sudoers.d/",
+			want: Rule{Action: "always", List: "exit", Syscalls: []string{}, Fields: []string{},
+				Path: "/etc/sudoers.d", Permissions: "rwxa", Keys: []string{}},
+		},
+		{
+			line: "-a exit,always -F arch=x86_64 -S open,creat -F exit=-EACCES -F auid>=1000 -F auid!=4294967295 -F key=access",
+			want: Rule{Action: "always", List: "exit", Arch: "b64", Syscalls: []string{"creat", "open"},
+				Fields: []string{"auid!=unset", "auid>=1000", "exit=-EACCES"}, Keys: []string{"access"}},
+		},
+		{
+			line: "-a always,exit -F arch=b64 -C euid!=uid -F euid=0 -S execve -k user_emulation",
+			want: Rule{Action: "always", List: "exit", Arch: "b64", Syscalls: []string{"execve"},
+				Fields: []string{"C:euid!=uid", "euid=0"}, Keys: []string{"user_emulation"}},
+		},
+		{
+			line: "-e 2",
+			want: Rule{Control: "-e", Value: "2", Syscalls: []string{}, Fields: []string{}, Keys: []string{}},
+		},
+		{
+			line: "-D",
+			want: Rule{Control: "-D", Syscalls: []string{}, Fields: []string{}, Keys: []string{}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			rule, err := Parse(c.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.want.Raw = c.line
+			assert.Equal(t, c.want, *rule)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"-a always,exit -S",
+		"-a always,exit -F arch",
+		"-F arch=b64 -S open",
+		"-w /etc/group extra",
+	} {
+		_, err := Parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{
+			a:    "-a always,exit -F arch=b64 -S adjtimex,settimeofday -k time-change",
+			b:    "-a exit,always -S settimeofday -F key=time-change -S adjtimex -F arch=b64",
+			want: true,
+		},
+		{
+			// auditctl -l lists watches added with -a as fields
+			a:    "-w /etc/passwd -p wa -k identity",
+			b:    "-a always,exit -S all -F path=/etc/passwd -F perm=aw -F key=identity",
+			want: true,
+		},
+		{
+			a:    "-a always,exit -F path=/usr/bin/sudo -F perm=x -F auid>=1000 -F auid!=unset -k privileged",
+			b:    "-a always,exit -F path=/usr/bin/sudo -F perm=x -F auid>=1000 -F auid!=-1 -k privileged",
+			want: true,
+		},
+		{
+			a:    "-w /etc/passwd -p wa -k identity",
+			b:    "-w /etc/passwd -p w -k identity",
+			want: false,
+		},
+		{
+			a:    "-a always,exit -F arch=b64 -S sethostname -k system-locale",
+			b:    "-a always,exit -F arch=b32 -S sethostname -k system-locale",
+			want: false,
+		},
+		{
+			a:    "-w /var/log/sudo.log -p wa -k sudo_log",
+			b:    "-w /var/log/sudo.log -p wa -k actions",
+			want: false,
+		},
+	}
+	for _, c := range cases {
+		a, err := Parse(c.a)
+		assert.NoError(t, err)
+		b, err := Parse(c.b)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, a.Equivalent(b), "%s and %s", c.a, c.b)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+## First rule - delete all
+-D
+
+# Increase the buffers to survive stress events.
+-b 8192
+-w /etc/group -p wa -k identity
+
+-e 2
+`))
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 4) {
+		assert.Equal(t, "-D", rules[0].Canonical())
+		assert.Equal(t, "-b 8192", rules[1].Canonical())
+		assert.Equal(t, "-a always,exit -F path=/etc/group -F perm=wa -F key=identity", rules[2].Canonical())
+		assert.True(t, Contains(rules, &Rule{Control: "-e", Value: "2"}))
+	}
+
+	rules, err = ParseRules([]byte("No rules\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = ParseRules([]byte("-w /etc/group -p\n"))
+	assert.Error(t, err)
+}
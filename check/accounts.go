@@ -0,0 +1,359 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeAccounts audits the local user and group databases.
+const TypeAccounts = "accounts"
+
+// AccountsAudit reads /etc/passwd, /etc/shadow, /etc/group and /etc/gshadow.
+type AccountsAudit struct {
+	EtcRoot string `yaml:"etc_root"`
+}
+
+// Accounts is the local user and group database, along with aggregates
+// which are awkward to express as a path test.
+type Accounts struct {
+	Users  []*AccountUser  `json:"users"`
+	Groups []*AccountGroup `json:"groups"`
+
+	DuplicateUIDs       []int    `json:"duplicate_uids"`
+	DuplicateGIDs       []int    `json:"duplicate_gids"`
+	DuplicateUserNames  []string `json:"duplicate_user_names"`
+	DuplicateGroupNames []string `json:"duplicate_group_names"`
+	UID0Users           []string `json:"uid0_users"`
+	EmptyPasswordUsers  []string `json:"empty_password_users"`
+	UsersWithoutShadow  []string `json:"users_without_shadow"`
+	// OrphanedGIDs are primary groups of users which are not defined in /etc/group
+	OrphanedGIDs []int `json:"orphaned_gids"`
+	// UnknownMembers are group members which are not defined in /etc/passwd
+	UnknownMembers []string `json:"unknown_members"`
+	// LegacyEntries are NIS "+" entries, e.g. "/etc/passwd:+::::::"
+	LegacyEntries []string `json:"legacy_entries"`
+}
+
+// AccountUser is an entry of /etc/passwd, with its /etc/shadow entry.
+// Passwords are never returned, as the output of audits ends up in reports,
+// only their status.
+type AccountUser struct {
+	Name           string       `json:"name"`
+	PasswordStatus string       `json:"password_status"`
+	UID            int          `json:"uid"`
+	GID            int          `json:"gid"`
+	GECOS          string       `json:"gecos"`
+	Home           string       `json:"home"`
+	Shell          string       `json:"shell"`
+	Shadow         *ShadowEntry `json:"shadow,omitempty"`
+}
+
+// ShadowEntry is an entry of /etc/shadow. Fields which are not set are omitted.
+type ShadowEntry struct {
+	PasswordStatus string `json:"password_status"`
+	// HashAlgorithm is the algorithm of a password which is set, e.g. sha512
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	LastChange    *int64 `json:"last_change,omitempty"`
+	MinDays       *int64 `json:"min_days,omitempty"`
+	MaxDays       *int64 `json:"max_days,omitempty"`
+	WarnDays      *int64 `json:"warn_days,omitempty"`
+	InactiveDays  *int64 `json:"inactive_days,omitempty"`
+	Expire        *int64 `json:"expire,omitempty"`
+}
+
+// AccountGroup is an entry of /etc/group, with its /etc/gshadow entry.
+type AccountGroup struct {
+	Name           string        `json:"name"`
+	PasswordStatus string        `json:"password_status"`
+	GID            int           `json:"gid"`
+	Members        []string      `json:"members"`
+	PrimaryUsers   []string      `json:"primary_users"`
+	Gshadow        *GshadowEntry `json:"gshadow,omitempty"`
+}
+
+// GshadowEntry is an entry of /etc/gshadow.
+type GshadowEntry struct {
+	PasswordStatus string   `json:"password_status"`
+	HashAlgorithm  string   `json:"hash_algorithm,omitempty"`
+	Admins         []string `json:"admins"`
+	Members        []string `json:"members"`
+}
+
+// Password statuses
+const (
+	passwordEmpty  = "empty"
+	passwordLocked = "locked"
+	passwordSet    = "set"
+	// passwordShadowed is a password of /etc/passwd or /etc/group which is
+	// in the shadow file, "x"
+	passwordShadowed = "shadowed"
+)
+
+// hashAlgorithms are the algorithms of crypt(5) by the prefix of their hashes.
+var hashAlgorithms = map[string]string{
+	"1":    "md5",
+	"2a":   "bcrypt",
+	"2b":   "bcrypt",
+	"2y":   "bcrypt",
+	"5":    "sha256",
+	"6":    "sha512",
+	"7":    "scrypt",
+	"y":    "yescrypt",
+	"gy":   "gost-yescrypt",
+	"sha1": "sha1crypt",
+}
+
+// Execute returns the accounts as a single document.
+func (a *AccountsAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	accounts, err := readAccounts(target.fs(), target.path(rootOrDefault(a.EtcRoot, "/etc")))
+	if err != nil {
+		return auditFailed(err)
+	}
+	return jsonResult(accounts)
+}
+
+func readAccounts(fsys targetFS, etcRoot string) (*Accounts, error) {
+	accounts := &Accounts{}
+	files := map[string][][]string{}
+	for _, name := range []string{"passwd", "shadow", "group", "gshadow"} {
+		path := filepath.Join(etcRoot, name)
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			// shadow files are optional, but must be readable when present
+			if os.IsNotExist(err) && (name == "shadow" || name == "gshadow") {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		files[name] = parseColonFile(path, data, &accounts.LegacyEntries)
+	}
+
+	shadow := map[string]*ShadowEntry{}
+	for _, fields := range files["shadow"] {
+		shadow[fields[0]] = parseShadowEntry(fields)
+	}
+
+	for _, fields := range files["passwd"] {
+		if len(fields) < 7 {
+			continue
+		}
+		uid, uidErr := strconv.Atoi(fields[2])
+		gid, gidErr := strconv.Atoi(fields[3])
+		if uidErr != nil || gidErr != nil {
+			continue
+		}
+		accounts.Users = append(accounts.Users, &AccountUser{
+			Name:           fields[0],
+			PasswordStatus: passwdStatus(fields[1]),
+			UID:            uid,
+			GID:            gid,
+			GECOS:          fields[4],
+			Home:           fields[5],
+			Shell:          fields[6],
+			Shadow:         shadow[fields[0]],
+		})
+	}
+
+	gshadow := map[string]*GshadowEntry{}
+	for _, fields := range files["gshadow"] {
+		if len(fields) < 4 {
+			continue
+		}
+		status, algorithm := passwordStatus(fields[1])
+		gshadow[fields[0]] = &GshadowEntry{
+			PasswordStatus: status,
+			HashAlgorithm:  algorithm,
+			Admins:         splitList(fields[2]),
+			Members:        splitList(fields[3]),
+		}
+	}
+
+	for _, fields := range files["group"] {
+		if len(fields) < 4 {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		accounts.Groups = append(accounts.Groups, &AccountGroup{
+			Name:           fields[0],
+			PasswordStatus: passwdStatus(fields[1]),
+			GID:            gid,
+			Members:        splitList(fields[3]),
+			PrimaryUsers:   []string{},
+			Gshadow:        gshadow[fields[0]],
+		})
+	}
+
+	accounts.aggregate()
+	return accounts, nil
+}
+
+// aggregate fills in the aggregates from the parsed users and groups.
+func (a *Accounts) aggregate() {
+	a.DuplicateUIDs, a.DuplicateGIDs = []int{}, []int{}
+	a.DuplicateUserNames, a.DuplicateGroupNames = []string{}, []string{}
+	a.UID0Users, a.EmptyPasswordUsers, a.UsersWithoutShadow = []string{}, []string{}, []string{}
+	a.OrphanedGIDs, a.UnknownMembers = []int{}, []string{}
+	if a.Users == nil {
+		a.Users = []*AccountUser{}
+	}
+	if a.Groups == nil {
+		a.Groups = []*AccountGroup{}
+	}
+	if a.LegacyEntries == nil {
+		a.LegacyEntries = []string{}
+	}
+
+	uids, userNames := map[int]int{}, map[string]int{}
+	for _, u := range a.Users {
+		uids[u.UID]++
+		userNames[u.Name]++
+		if u.UID == 0 {
+			a.UID0Users = append(a.UID0Users, u.Name)
+		}
+		if u.PasswordStatus == passwordEmpty || (u.PasswordStatus == passwordShadowed && u.Shadow != nil && u.Shadow.PasswordStatus == passwordEmpty) {
+			a.EmptyPasswordUsers = append(a.EmptyPasswordUsers, u.Name)
+		}
+		if u.PasswordStatus == passwordShadowed && u.Shadow == nil {
+			a.UsersWithoutShadow = append(a.UsersWithoutShadow, u.Name)
+		}
+	}
+
+	gids, groupNames := map[int]int{}, map[string]int{}
+	groupsByGID := map[int]*AccountGroup{}
+	for _, g := range a.Groups {
+		gids[g.GID]++
+		groupNames[g.Name]++
+		if _, ok := groupsByGID[g.GID]; !ok {
+			groupsByGID[g.GID] = g
+		}
+		for _, member := range g.Members {
+			if _, ok := userNames[member]; !ok && !contains(a.UnknownMembers, member) {
+				a.UnknownMembers = append(a.UnknownMembers, member)
+			}
+		}
+	}
+
+	orphaned := map[int]bool{}
+	for _, u := range a.Users {
+		if g, ok := groupsByGID[u.GID]; ok {
+			g.PrimaryUsers = append(g.PrimaryUsers, u.Name)
+		} else if !orphaned[u.GID] {
+			orphaned[u.GID] = true
+			a.OrphanedGIDs = append(a.OrphanedGIDs, u.GID)
+		}
+	}
+
+	a.DuplicateUIDs = duplicateKeys(uids)
+	a.DuplicateGIDs = duplicateKeys(gids)
+	a.DuplicateUserNames = duplicateKeys(userNames)
+	a.DuplicateGroupNames = duplicateKeys(groupNames)
+}
+
+// parseColonFile splits the lines of a colon separated database file. NIS "+"
+// and "-" entries are recorded as legacy entries instead.
+func parseColonFile(path string, data []byte, legacy *[]string) [][]string {
+	var entries [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			*legacy = append(*legacy, path+":"+line)
+			continue
+		}
+		entries = append(entries, strings.Split(line, ":"))
+	}
+	return entries
+}
+
+func parseShadowEntry(fields []string) *ShadowEntry {
+	for len(fields) < 9 {
+		fields = append(fields, "")
+	}
+
+	entry := &ShadowEntry{}
+	entry.PasswordStatus, entry.HashAlgorithm = passwordStatus(fields[1])
+
+	days := []**int64{&entry.LastChange, &entry.MinDays, &entry.MaxDays, &entry.WarnDays, &entry.InactiveDays, &entry.Expire}
+	for i, field := range fields[2:8] {
+		if v, err := strconv.ParseInt(field, 10, 64); err == nil {
+			*days[i] = &v
+		}
+	}
+	return entry
+}
+
+// passwordStatus returns the status of the password field of a shadow file,
+// and the algorithm of its hash when it is set.
+func passwordStatus(password string) (status, algorithm string) {
+	switch {
+	case password == "":
+		return passwordEmpty, ""
+	case strings.HasPrefix(password, "!") || strings.HasPrefix(password, "*"):
+		return passwordLocked, ""
+	}
+	if !strings.HasPrefix(password, "$") {
+		// Traditional crypt(3) hashes have no prefix
+		return passwordSet, "des"
+	}
+	id, _, _ := strings.Cut(password[1:], "$")
+	if algorithm, ok := hashAlgorithms[id]; ok {
+		return passwordSet, algorithm
+	}
+	return passwordSet, "unknown"
+}
+
+// passwdStatus returns the status of the password field of /etc/passwd or
+// /etc/group, which is usually in the shadow file.
+func passwdStatus(password string) string {
+	if password == "x" {
+		return passwordShadowed
+	}
+	status, _ := passwordStatus(password)
+	return status
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// duplicateKeys returns the keys which were counted more than once, sorted.
+func duplicateKeys[K int | string](counts map[K]int) []K {
+	dups := []K{}
+	for k, n := range counts {
+		if n > 1 {
+			dups = append(dups, k)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i] < dups[j] })
+	return dups
+}
@@ -0,0 +1,194 @@
+package check
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testAccountFiles = map[string]string{
+	"etc/passwd": `root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+toor:x:0:0::/root:/bin/sh
+alice:x:1000:1000:Alice:/home/alice:/bin/bash
+bob::1001:1001:Bob:/home/bob:/bin/bash
+carol:x:1000:2000::/home/carol:/bin/bash
++::::::
+`,
+	"etc/shadow": `root:$6$abc:19000:0:99999:7:::
+daemon:*:19000:0:99999:7:::
+toor:!:19000::::::
+alice::19000:1:90:7:30::
+carol:$6$def:19000:1:365:7:::
+`,
+	"etc/group": `root:x:0:
+daemon:x:1:
+shadow:x:42:alice
+users:x:1000:alice,dave
+users:x:1001:
+`,
+	"etc/gshadow": `root:*::
+shadow:!:root:alice
+`,
+}
+
+func TestAccountsAudit_Execute(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, testAccountFiles)
+
+	out, errMsg, state := (&AccountsAudit{EtcRoot: filepath.Join(root, "etc")}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	var accounts Accounts
+	if err := json.Unmarshal([]byte(out), &accounts); err != nil {
+		t.Fatalf("failed to unmarshal %q: %v", out, err)
+	}
+
+	assert.Len(t, accounts.Users, 6)
+	assert.Len(t, accounts.Groups, 5)
+	assert.Equal(t, []int{0, 1000}, accounts.DuplicateUIDs)
+	assert.Equal(t, []int{}, accounts.DuplicateGIDs)
+	assert.Equal(t, []string{}, accounts.DuplicateUserNames)
+	assert.Equal(t, []string{"users"}, accounts.DuplicateGroupNames)
+	assert.Equal(t, []string{"root", "toor"}, accounts.UID0Users)
+	assert.Equal(t, []string{"alice", "bob"}, accounts.EmptyPasswordUsers)
+	assert.Equal(t, []string{}, accounts.UsersWithoutShadow)
+	assert.Equal(t, []int{2000}, accounts.OrphanedGIDs)
+	assert.Equal(t, []string{"dave"}, accounts.UnknownMembers)
+	assert.Equal(t, []string{filepath.Join(root, "etc/passwd") + ":+::::::"}, accounts.LegacyEntries)
+
+	alice := accounts.Users[3]
+	if assert.NotNil(t, alice.Shadow) {
+		assert.Equal(t, passwordEmpty, alice.Shadow.PasswordStatus)
+		assert.Equal(t, int64(90), *alice.Shadow.MaxDays)
+		assert.Equal(t, int64(30), *alice.Shadow.InactiveDays)
+		assert.Nil(t, alice.Shadow.Expire)
+	}
+	assert.Equal(t, passwordLocked, accounts.Users[2].Shadow.PasswordStatus)
+	assert.Equal(t, passwordSet, accounts.Users[0].Shadow.PasswordStatus)
+	assert.Equal(t, "sha512", accounts.Users[0].Shadow.HashAlgorithm)
+	assert.Equal(t, passwordShadowed, accounts.Users[0].PasswordStatus)
+	assert.Equal(t, passwordEmpty, accounts.Users[4].PasswordStatus)
+	// Hashes never reach the output
+	assert.NotContains(t, out, "$6$")
+
+	shadow := accounts.Groups[2]
+	assert.Equal(t, []string{"alice"}, shadow.Members)
+	assert.Equal(t, []string{}, shadow.PrimaryUsers)
+	if assert.NotNil(t, shadow.Gshadow) {
+		assert.Equal(t, []string{"root"}, shadow.Gshadow.Admins)
+		assert.Equal(t, passwordLocked, shadow.Gshadow.PasswordStatus)
+	}
+	assert.Equal(t, []string{"alice"}, accounts.Groups[3].PrimaryUsers)
+}
+
+func TestPasswordStatus(t *testing.T) {
+	cases := []struct {
+		password, status, algorithm string
+	}{
+		{"", passwordEmpty, ""},
+		{"!", passwordLocked, ""},
+		{"!$6$abc$def", passwordLocked, ""},
+		{"*", passwordLocked, ""},
+		{"$1$abc$def", passwordSet, "md5"},
+		{"$2b$10$abc", passwordSet, "bcrypt"},
+		{"$5$abc$def", passwordSet, "sha256"},
+		{"$6$abc$def", passwordSet, "sha512"},
+		{"$y$j9T$abc$def", passwordSet, "yescrypt"},
+		{"$z$abc", passwordSet, "unknown"},
+		{"abcdefghijklm", passwordSet, "des"},
+	}
+	for _, c := range cases {
+		status, algorithm := passwordStatus(c.password)
+		assert.Equal(t, c.status, status, c.password)
+		assert.Equal(t, c.algorithm, algorithm, c.password)
+	}
+}
+
+func TestAccountsAudit_MissingShadow(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/passwd": "root:x:0:0:root:/root:/bin/bash\n",
+		"etc/group":  "root:x:0:\n",
+	})
+
+	out, errMsg, state := (&AccountsAudit{EtcRoot: filepath.Join(root, "etc")}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	var accounts Accounts
+	if err := json.Unmarshal([]byte(out), &accounts); err != nil {
+		t.Fatalf("failed to unmarshal %q: %v", out, err)
+	}
+	assert.Equal(t, []string{"root"}, accounts.UsersWithoutShadow)
+}
+
+func TestAccountsAudit_MissingPasswd(t *testing.T) {
+	_, errMsg, state := (&AccountsAudit{EtcRoot: t.TempDir()}).Execute()
+	if state != WARN || errMsg == "" {
+		t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+	}
+}
+
+func TestAccountsAudit_Check(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, testAccountFiles)
+
+	controls := `---
+controls:
+id: 6
+text: "System Maintenance"
+groups:
+- id: 6.2
+  text: "User and Group Settings"
+  checks:
+    - id: 6.2.1
+      text: "Ensure root is the only UID 0 account"
+      audittype: "accounts"
+      audit:
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{.uid0_users[*]}"
+          compare:
+            op: eq
+            value: root
+      scored: true
+    - id: 6.2.2
+      text: "Ensure shadow group is empty"
+      audittype: "accounts"
+      audit:
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{.groups[?(@.name==\"shadow\")].members}"
+          compare:
+            op: eq
+            value: "[]"
+      scored: true
+    - id: 6.2.3
+      text: "Ensure no duplicate GIDs exist"
+      audittype: "accounts"
+      audit:
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{.duplicate_gids}"
+          compare:
+            op: eq
+            value: "[]"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Fail: 2}, summary)
+}
@@ -0,0 +1,79 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/bench-common/auditeval"
+)
+
+// AppliesIf is a lightweight audit deciding whether a check, or the checks of
+// a group, apply to the system, e.g. whether docker is installed. Without
+// tests, the checks apply when the audit succeeds.
+type AppliesIf struct {
+	AuditType     AuditType        `yaml:"audittype" json:"audit_type,omitempty"`
+	Audit         interface{}      `yaml:"audit" json:"audit,omitempty"`
+	Tests         *auditeval.Tests `yaml:"tests" json:"-"`
+	auditer       Auditer
+	customConfigs []interface{}
+}
+
+// notApplicable runs the audit, and returns the reason the checks do not
+// apply, or an empty string when they do.
+func (a *AppliesIf) notApplicable(id string) string {
+	result, errmsgs, state := runAuditCommands(BaseCheck{
+		AuditType:     a.AuditType,
+		Audit:         a.Audit,
+		auditer:       a.auditer,
+		customConfigs: a.customConfigs,
+	})
+	switch {
+	case result.CommandNotFound && !a.Tests.UsesSources():
+		return "Not applicable, command not found: " + strings.TrimSpace(orDefault(result.Stderr, errmsgs))
+	case state != "":
+		// A native audit which could not be carried out, e.g. no docker socket
+		return "Not applicable, " + strings.TrimSpace(errmsgs)
+	case a.Tests == nil:
+		if errmsgs != "" {
+			return fmt.Sprintf("Not applicable, audit exited with code %d", result.ExitCode)
+		}
+		return ""
+	}
+
+	finalOutput := executeTests(a.Tests, a.auditer, result, id, false)
+	if finalOutput == nil || !finalOutput.TestResult {
+		expected := ""
+		if finalOutput != nil {
+			expected = finalOutput.ExpectedResult
+		}
+		return "Not applicable, requires " + orDefault(expected, "applies_if tests to pass")
+	}
+	return ""
+}
+
+// notApplicable returns the reason the checks of the group do not apply. The
+// audit runs once, the first time the group is asked.
+func (g *Group) notApplicable() string {
+	if g.AppliesIf == nil {
+		return ""
+	}
+	if !g.probed {
+		g.probed = true
+		g.notApplicableReason = g.AppliesIf.notApplicable(g.ID)
+	}
+	return g.notApplicableReason
+}
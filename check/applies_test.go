@@ -0,0 +1,182 @@
+package check
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/stretchr/testify/assert"
+)
+
+const appliesControls = `---
+controls:
+id: 2
+text: "Components"
+groups:
+- id: 2.1
+  text: "Docker"
+  checks:
+    - id: 2.1.1
+      text: "Ensure the docker daemon is installed"
+      audit: "echo ok"
+      applies_if:
+        audit: "nosuchcommand-bench --version"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+    - id: 2.1.2
+      text: "Ensure the daemon runs"
+      audit: "echo ok"
+      applies_if:
+        audit: "echo 'Server Version: 24.0.7'"
+        tests:
+          test_items:
+          - flag: "Server Version"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+    - id: 2.1.3
+      text: "Ensure the daemon is not in debug mode"
+      audit: "echo ok"
+      applies_if:
+        audit: "echo 'Client only'"
+        tests:
+          test_items:
+          - flag: "Server Version"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+- id: 2.2
+  text: "etcd"
+  applies_if:
+    audit: "echo probed >> %[1]s; false"
+  checks:
+    - id: 2.2.1
+      text: "Ensure the etcd data directory is restricted"
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+    - id: 2.2.2
+      text: "Ensure etcd uses TLS"
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+- id: 2.3
+  text: "Skipped"
+  type: skip
+  applies_if:
+    audit: "echo probed >> %[1]s; false"
+  checks:
+    - id: 2.3.1
+      text: "Ensure nothing"
+      audit: "echo ok"
+`
+
+func TestControls_RunGroupAppliesIf(t *testing.T) {
+	probes := filepath.Join(t.TempDir(), "probes")
+	controls, err := NewBench().NewControls([]byte(fmt.Sprintf(appliesControls, probes)), nil)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	checks := controls.checksByID()
+	summary := controls.RunGroup()
+
+	assert.EqualValues(t, NOTAPPLICABLE, checks["2.1.1"].State)
+	assert.True(t, strings.HasPrefix(checks["2.1.1"].Reason, "Not applicable, command not found: "), checks["2.1.1"].Reason)
+	assert.EqualValues(t, PASS, checks["2.1.2"].State)
+	assert.EqualValues(t, NOTAPPLICABLE, checks["2.1.3"].State)
+	assert.Equal(t, "Not applicable, requires 'Server Version' Is present", checks["2.1.3"].Reason)
+
+	assert.EqualValues(t, NOTAPPLICABLE, checks["2.2.1"].State)
+	assert.EqualValues(t, NOTAPPLICABLE, checks["2.2.2"].State)
+	assert.Equal(t, "Not applicable, audit exited with code 1", checks["2.2.2"].Reason)
+	assert.EqualValues(t, INFO, checks["2.3.1"].State)
+
+	// The group probe runs once, and not at all for a skipped group
+	out, err := os.ReadFile(probes)
+	if err != nil {
+		t.Fatalf("failed to read probes: %v", err)
+	}
+	assert.Equal(t, "probed\n", string(out))
+
+	assert.Equal(t, Summary{Pass: 1, Info: 1, NotApplicable: 4}, summary)
+	assert.Equal(t, 2, controls.Groups[0].NotApplicable)
+	assert.Equal(t, 2, controls.Groups[1].NotApplicable)
+
+	junit, err := controls.JUnit()
+	if err != nil {
+		t.Fatalf("failed to encode JUnit: %v", err)
+	}
+	var suite reporters.JUnitTestSuite
+	if err := xml.Unmarshal(junit, &suite); err != nil {
+		t.Fatalf("failed to decode JUnit: %v", err)
+	}
+	assert.Equal(t, 6, suite.Tests)
+	assert.Equal(t, 0, suite.Failures)
+	if assert.NotNil(t, suite.TestCases[2].Skipped) {
+		assert.Equal(t, checks["2.1.3"].Reason, suite.TestCases[2].Skipped.Message)
+	}
+}
+
+func TestControls_RunChecksAppliesIf(t *testing.T) {
+	probes := filepath.Join(t.TempDir(), "probes")
+	controls, err := NewBench().NewControls([]byte(fmt.Sprintf(appliesControls, probes)), nil)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	summary := controls.RunChecks("2.2.2")
+
+	assert.Equal(t, Summary{NotApplicable: 1}, summary)
+	assert.Equal(t, "2.2.2", controls.Groups[0].Checks[0].ID)
+	assert.EqualValues(t, NOTAPPLICABLE, controls.Groups[0].Checks[0].State)
+}
+
+func TestAppliesIf_NativeAudit(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(`---
+controls:
+groups:
+- id: 1
+  checks:
+  - id: 1.1
+    audit: "echo ok"
+    applies_if:
+      audittype: docker
+      audit:
+        socket: /nonexistent/docker.sock
+    tests:
+      test_items:
+      - flag: "ok"
+`), nil)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	controls.RunGroup()
+
+	c := controls.Groups[0].Checks[0]
+	assert.EqualValues(t, NOTAPPLICABLE, c.State)
+	assert.True(t, strings.HasPrefix(c.Reason, "Not applicable, "), c.Reason)
+}
+
+func TestAppliesIf_UnknownAuditType(t *testing.T) {
+	_, err := NewBench().NewControls([]byte(`---
+controls:
+groups:
+- id: 1
+  applies_if:
+    audittype: nosuchtype
+  checks:
+  - id: 1.1
+`), nil)
+	assert.EqualError(t, err, "audit type nosuchtype is not registered")
+}
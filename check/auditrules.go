@@ -0,0 +1,102 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/bench-common/auditrules"
+)
+
+// TypeAuditRules audits the rules of the Linux audit system.
+const TypeAuditRules = "audit_rules"
+
+// audit_rules sources
+const (
+	auditRulesFiles  = "files"
+	auditRulesLoaded = "loaded"
+)
+
+const defaultAuditRulesDir = "/etc/audit/rules.d"
+
+// AuditRulesAudit reads the rules files in /etc/audit/rules.d, or the rules
+// loaded in the kernel as listed by `auditctl -l`.
+type AuditRulesAudit struct {
+	// Source is "files" (the default) or "loaded"
+	Source   string `yaml:"source"`
+	RulesDir string `yaml:"rules_dir"`
+	Auditctl string `yaml:"auditctl"`
+}
+
+// readsRuntime tests if the rules are loaded in the kernel, which offline
+// targets don't have.
+func (a *AuditRulesAudit) readsRuntime() bool {
+	return a.Source == auditRulesLoaded
+}
+
+// AuditRule is a normalized audit rule, with the file it was read from.
+type AuditRule struct {
+	*auditrules.Rule
+	File string `json:"file,omitempty"`
+}
+
+// Execute returns a row for each rule, in the order they are loaded.
+func (a *AuditRulesAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	var rules []AuditRule
+	switch a.Source {
+	case "", auditRulesFiles:
+		dir := target.path(rootOrDefault(a.RulesDir, defaultAuditRulesDir))
+		// augenrules loads the files in the lexical order of their names
+		files, err := target.fs().Glob(filepath.Join(dir, "*.rules"))
+		if err != nil {
+			return auditFailed(err)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			data, err := target.fs().ReadFile(file)
+			if err != nil {
+				return auditFailed(fmt.Errorf("failed to read %s: %v", file, err))
+			}
+			parsed, err := auditrules.ParseRules(data)
+			if err != nil {
+				return auditFailed(fmt.Errorf("failed to parse %s: %v", file, err))
+			}
+			for _, rule := range parsed {
+				rules = append(rules, AuditRule{Rule: rule, File: file})
+			}
+		}
+	case auditRulesLoaded:
+		auditctl := orDefault(a.Auditctl, "auditctl")
+		out, err := target.output(auditctl, "-l")
+		if err != nil {
+			return auditFailed(fmt.Errorf("failed to run %s -l: %v", auditctl, err))
+		}
+		parsed, err := auditrules.ParseRules(out)
+		if err != nil {
+			return auditFailed(err)
+		}
+		for _, rule := range parsed {
+			rules = append(rules, AuditRule{Rule: rule})
+		}
+	default:
+		return auditFailed(fmt.Errorf("unknown audit_rules source %q, expected one of %s", a.Source,
+			strings.Join([]string{auditRulesFiles, auditRulesLoaded}, ", ")))
+	}
+	return jsonLinesResult(rules)
+}
@@ -0,0 +1,142 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeAuditRules creates a rules.d directory with rules written in a
+// different order and spelling than the CIS benchmark.
+func writeAuditRules(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base-config.rules": "## First rule - delete all\n-D\n-b 8192\n",
+		"50-identity.rules":    "-w /etc/group -p aw -k identity\n-w /etc/passwd -k identity -p wa\n",
+		"50-time-change.rules": "-a exit,always -F arch=b64 -S settimeofday,adjtimex -F key=time-change\n",
+		"99-finalize.rules":    "-e 2\n",
+		"README":               "-w /etc/shadow -p wa -k identity\n",
+	})
+	return dir
+}
+
+func TestAuditRulesAudit_Files(t *testing.T) {
+	dir := writeAuditRules(t)
+
+	audit := AuditRulesAudit{RulesDir: dir}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	rules := parseRows[AuditRule](t, out)
+	if assert.Len(t, rules, 6) {
+		assert.Equal(t, "-D", rules[0].Control)
+		assert.Equal(t, filepath.Join(dir, "10-base-config.rules"), rules[0].File)
+		assert.Equal(t, "/etc/passwd", rules[3].Path)
+		assert.Equal(t, "wa", rules[3].Permissions)
+		assert.Equal(t, []string{"adjtimex", "settimeofday"}, rules[4].Syscalls)
+		assert.Equal(t, "-e", rules[5].Control)
+		assert.Equal(t, "2", rules[5].Value)
+	}
+}
+
+func TestAuditRulesAudit_Loaded(t *testing.T) {
+	auditctl := filepath.Join(t.TempDir(), "auditctl")
+	script := "#!/bin/sh\necho '-w /etc/group -p wa -k identity'\necho '-a always,exit -F arch=b64 -S adjtimex,settimeofday -F key=time-change'\n"
+	if err := os.WriteFile(auditctl, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write %s: %v", auditctl, err)
+	}
+
+	audit := AuditRulesAudit{Source: "loaded", Auditctl: auditctl}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	rules := parseRows[AuditRule](t, out)
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, "/etc/group", rules[0].Path)
+		assert.Empty(t, rules[0].File)
+		assert.Equal(t, "b64", rules[1].Arch)
+	}
+}
+
+func TestAuditRulesAudit_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"50-bad.rules": "-w /etc/group -p\n"})
+
+	cases := []struct {
+		name  string
+		audit AuditRulesAudit
+	}{
+		{name: "invalid rule", audit: AuditRulesAudit{RulesDir: dir}},
+		{name: "unknown source", audit: AuditRulesAudit{Source: "kernel"}},
+		{name: "no auditctl", audit: AuditRulesAudit{Source: "loaded", Auditctl: filepath.Join(dir, "auditctl")}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, errMsg, state := c.audit.Execute()
+			assert.NotEmpty(t, errMsg)
+			assert.EqualValues(t, WARN, state)
+		})
+	}
+}
+
+func TestAuditRulesAudit_Check(t *testing.T) {
+	dir := writeAuditRules(t)
+
+	controls := `---
+controls:
+id: 4
+text: "Logging and Auditing"
+groups:
+- id: 4.1
+  text: "Configure System Accounting (auditd)"
+  checks:
+    - id: 4.1.3.4
+      text: "Ensure events that modify date and time information are collected"
+      audittype: "audit_rules"
+      audit:
+        rules_dir: "` + dir + `"
+      tests:
+        test_items:
+        - compare:
+            op: has_audit_rule
+            value: "-a always,exit -F arch=b64 -S adjtimex,settimeofday -k time-change"
+      scored: true
+    - id: 4.1.3.8
+      text: "Ensure events that modify user/group information are collected"
+      audittype: "audit_rules"
+      audit:
+        rules_dir: "` + dir + `"
+      tests:
+        test_items:
+        - compare:
+            op: has_audit_rule
+            value: |
+              -w /etc/group -p wa -k identity
+              -w /etc/passwd -p wa -k identity
+              -w /etc/shadow -p wa -k identity
+      scored: true
+    - id: 4.1.3.20
+      text: "Ensure the audit configuration is immutable"
+      audittype: "audit_rules"
+      audit:
+        rules_dir: "` + dir + `"
+      tests:
+        test_items:
+        - compare:
+            op: has_audit_rule
+            value: "-e 2"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2, Fail: 1}, summary)
+}
@@ -33,6 +33,24 @@ type bench struct {
 	auditTypeRegistry map[AuditType]func() interface{}
 }
 
+// builtinAuditTypes holds the native audit types which are available to every Bench.
+// Types registered with RegisterAuditType take precedence over these.
+var builtinAuditTypes = map[AuditType]func() interface{}{
+	TypeSysctl:      func() interface{} { return &SysctlAudit{} },
+	TypeMount:       func() interface{} { return &MountAudit{} },
+	TypeAccounts:    func() interface{} { return &AccountsAudit{} },
+	TypeSockets:     func() interface{} { return &SocketsAudit{} },
+	TypeSystemdUnit: func() interface{} { return &SystemdUnitAudit{} },
+	TypeDocker:      func() interface{} { return &DockerAudit{} },
+	TypeHTTP:        func() interface{} { return &HTTPAudit{} },
+	TypeTLSProbe:    func() interface{} { return &TLSProbeAudit{} },
+	TypeFSWalk:      func() interface{} { return &FSWalkAudit{} },
+	TypeLSM:         func() interface{} { return &LSMAudit{} },
+	TypeAuditRules:  func() interface{} { return &AuditRulesAudit{} },
+	TypePackages:    func() interface{} { return &PackagesAudit{} },
+	TypeExec:        func() interface{} { return &ExecAudit{} },
+}
+
 // NewBench returns a new Bench
 func NewBench() Bench {
 	return &bench{auditTypeRegistry: make(map[AuditType]func() interface{})}
@@ -66,6 +84,9 @@ func (b *bench) NewControls(in []byte, definitions []string, customConfigs ...in
 	defer logger.Sync() // nolint: errcheck
 
 	c.customConfigs = customConfigs
+	if target := targetFrom(customConfigs); target != defaultTarget {
+		c.Target = target.metadata()
+	}
 	if len(definitions) > 0 {
 		c.DefinedConstraints = map[string][]string{}
 		for _, val := range definitions {
@@ -79,9 +100,31 @@ func (b *bench) NewControls(in []byte, definitions []string, customConfigs ...in
 			}
 		}
 	}
+	if d, ok := detectFrom(customConfigs); ok {
+		detected, err := detect(targetFrom(customConfigs), d)
+		if err != nil {
+			return nil, err
+		}
+		c.Detected = detected
+		if c.DefinedConstraints == nil {
+			c.DefinedConstraints = map[string][]string{}
+		}
+		// Values defined explicitly override the detected ones
+		for key, values := range detected {
+			if _, ok := c.DefinedConstraints[key]; !ok {
+				c.DefinedConstraints[key] = values
+			}
+		}
+	}
 	if err := b.extractAllAudits(c); err != nil {
 		return nil, err
 	}
+	if err := c.checkDependencies(); err != nil {
+		return nil, err
+	}
+	if err := c.checkConstraints(); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -99,7 +142,9 @@ func (b *bench) convertAuditToRegisteredType(auditType AuditType, audit interfac
 	}
 
 	if callback, ok = b.auditTypeRegistry[auditType]; !ok {
-		return nil, fmt.Errorf("audit type %v is not registered", auditType)
+		if callback, ok = builtinAuditTypes[auditType]; !ok {
+			return nil, fmt.Errorf("audit type %v is not registered", auditType)
+		}
 	}
 
 	o := callback()
@@ -114,25 +159,49 @@ func (b *bench) convertAuditToRegisteredType(auditType AuditType, audit interfac
 }
 
 func (b *bench) extractAllAudits(controls *Controls) (err error) {
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
+		if err == nil {
+			err = b.extractGroupAudits(controls, group)
+		}
+	})
+	return err
+}
+
+func (b *bench) extractGroupAudits(controls *Controls, group *Group) (err error) {
 	var audit Auditer
-	for _, group := range controls.Groups {
-		for _, check := range group.Checks {
-			if check.SubChecks == nil {
-				if audit, err = b.convertAuditToRegisteredType(check.AuditType, check.Audit); err != nil {
+	if err = b.extractAppliesIf(controls, group.AppliesIf); err != nil {
+		return err
+	}
+	for _, check := range group.Checks {
+		if err = b.extractAppliesIf(controls, check.AppliesIf); err != nil {
+			return err
+		}
+		if check.SubChecks == nil {
+			if audit, err = b.convertAuditToRegisteredType(check.AuditType, check.Audit); err != nil {
+				return err
+			}
+			check.auditer = audit
+			check.customConfigs = controls.customConfigs
+		} else {
+			for _, subCheck := range check.SubChecks {
+				if audit, err = b.convertAuditToRegisteredType(subCheck.AuditType, subCheck.Audit); err != nil {
 					return err
 				}
-				check.auditer = audit
-				check.customConfigs = controls.customConfigs
-			} else {
-				for _, subCheck := range check.SubChecks {
-					if audit, err = b.convertAuditToRegisteredType(subCheck.AuditType, subCheck.Audit); err != nil {
-						return err
-					}
-					subCheck.auditer = audit
-					subCheck.customConfigs = controls.customConfigs
-				}
+				subCheck.auditer = audit
+				subCheck.customConfigs = controls.customConfigs
 			}
 		}
 	}
 	return err
 }
+
+func (b *bench) extractAppliesIf(controls *Controls, appliesIf *AppliesIf) (err error) {
+	if appliesIf == nil {
+		return nil
+	}
+	if appliesIf.auditer, err = b.convertAuditToRegisteredType(appliesIf.AuditType, appliesIf.Audit); err != nil {
+		return err
+	}
+	appliesIf.customConfigs = controls.customConfigs
+	return nil
+}
@@ -16,9 +16,13 @@ package check
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/aquasecurity/bench-common/auditeval"
@@ -40,8 +44,16 @@ type Audit string
 
 // Execute method called by the main logic to execute the Audit's Execute type.
 func (audit Audit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	res, errMessage, state := audit.ExecuteResult(customConfig...)
+	return res.Output, errMessage, state
+}
+
+// ExecuteResult runs the audit with the shell, reporting its stdout, stderr,
+// exit code and duration separately. The output of the audit combines stdout
+// and stderr. The shell is run in the target, with its shell wrapper.
+func (audit Audit) ExecuteResult(customConfig ...interface{}) (result AuditResult, errMessage string, state State) {
 
-	res, err := runAudit(string(audit))
+	res, err := runAuditResult(targetFrom(customConfig), string(audit))
 
 	// Errors mean the audit command failed, but that might be what we expect
 	// for example, if we grep for something that is not found, there is a non-zero exit code
@@ -50,7 +62,7 @@ func (audit Audit) Execute(customConfig ...interface{}) (result string, errMessa
 	if err != nil {
 		errMessage = err.Error()
 	}
-	return string(res), errMessage, ""
+	return res, errMessage, ""
 }
 
 const (
@@ -62,6 +74,9 @@ const (
 	WARN = "WARN"
 	// INFO informational message
 	INFO = "INFO"
+	// NOTAPPLICABLE check does not apply to the system, e.g. the component
+	// it audits is not installed.
+	NOTAPPLICABLE = "NOT_APPLICABLE"
 	// SKIP for when a check should be skipped.
 	SKIP = "skip"
 )
@@ -110,9 +125,19 @@ type Check struct {
 	ExpectedResult string `json:"expected_result"`
 	Scored         bool   `json:"scored"`
 	IsMultiple     bool   `yaml:"use_multiple_values"`
-	auditer        Auditer
-	customConfigs  []interface{}
-	Reason         string `json:"reason,omitempty"`
+	// Constraints restrict the check, e.g. to a platform.
+	Constraints map[string][]string `yaml:"constraints" json:"constraints,omitempty"`
+	// DependsOn are the checks which must end in a required state for the
+	// check to run.
+	DependsOn []Dependency `yaml:"depends_on" json:"depends_on,omitempty"`
+	// AppliesIf decides whether the check applies to the system.
+	AppliesIf *AppliesIf `yaml:"applies_if" json:"-"`
+	// Selection explains why the sub checks were, or weren't, selected.
+	Selection     []string `yaml:"-" json:"selection,omitempty"`
+	auditer       Auditer
+	customConfigs []interface{}
+	skipReason    string
+	Reason        string `json:"reason,omitempty"`
 }
 
 // Group is a collection of similar checks.
@@ -122,11 +147,19 @@ type Group struct {
 	Text        string              `json:"-"`
 	Constraints map[string][]string `yaml:"constraints"`
 	Type        string              `yaml:"type" json:"type"`
-	Checks      []*Check            `json:"results"`
-	Pass        int                 `json:"pass"` // Tests with no type that passed
-	Fail        int                 `json:"fail"` // Tests with no type that failed
-	Warn        int                 `json:"warn"` // Tests of type manual won't be run and will be marked as Warn
-	Info        int                 `json:"info"` // Tests of type skip won't be run and will be marked as Info
+	// AppliesIf decides whether the checks of the group apply to the system.
+	AppliesIf *AppliesIf `yaml:"applies_if" json:"-"`
+	// Groups are the sub groups of the group. They inherit its constraints,
+	// type and applies_if, and its counters include theirs.
+	Groups              []*Group `yaml:"groups" json:"groups,omitempty"`
+	Checks              []*Check `json:"results"`
+	Pass                int      `json:"pass"`           // Tests with no type that passed
+	Fail                int      `json:"fail"`           // Tests with no type that failed
+	Warn                int      `json:"warn"`           // Tests of type manual won't be run and will be marked as Warn
+	Info                int      `json:"info"`           // Tests of type skip won't be run and will be marked as Info
+	NotApplicable       int      `json:"not_applicable"` // Tests which don't apply to the system
+	probed              bool
+	notApplicableReason string
 }
 
 // Run executes the audit commands specified in a check and outputs
@@ -141,7 +174,14 @@ func (c *Check) Run(definedConstraints map[string][]string) {
 	logger.Warn("----- Running check  ----- ", zap.String("check ID", c.ID))
 	// If check type is skip, force result to INFO
 	if c.Type == SKIP {
-		c.Reason = "Test marked as skip"
+		c.Reason = orDefault(c.skipReason, "Test marked as skip")
+		c.State = INFO
+		logger.Warn("", zap.String("Reason", c.Reason))
+		return
+	}
+
+	if reason := unmetConstraints("Check", c.Constraints, definedConstraints); reason != "" {
+		c.Reason = reason
 		c.State = INFO
 		logger.Warn("", zap.String("Reason", c.Reason))
 		return
@@ -155,6 +195,15 @@ func (c *Check) Run(definedConstraints map[string][]string) {
 		return
 	}
 
+	if c.AppliesIf != nil {
+		if reason := c.AppliesIf.notApplicable(c.ID); reason != "" {
+			c.Reason = reason
+			c.State = NOTAPPLICABLE
+			logger.Warn("", zap.String("Reason", c.Reason))
+			return
+		}
+	}
+
 	// Since this is an Scored check
 	// without tests return a 'WARN' to alert
 	// the user that this check needs attention
@@ -178,10 +227,10 @@ func (c *Check) Run(definedConstraints map[string][]string) {
 			customConfigs: c.customConfigs,
 		}
 	} else {
-		subCheck = getFirstValidSubCheck(c.SubChecks, definedConstraints)
+		subCheck, c.Selection = selectSubCheck(c.SubChecks, definedConstraints)
 
 		if subCheck == nil {
-			c.Reason = "Failed to find a valid sub check, check your constraints "
+			c.Reason = "Failed to find a valid sub check, check your constraints"
 			c.State = WARN
 			logger.Debug("Failed to find a valid sub check, check your constraints")
 			logger.Warn("", zap.String("Reason", c.Reason))
@@ -189,25 +238,39 @@ func (c *Check) Run(definedConstraints map[string][]string) {
 		}
 	}
 
-	var out, errmsgs string
+	if target := targetFrom(subCheck.customConfigs); !target.applicable(subCheck.AuditType, subCheck.auditer) {
+		c.Reason = fmt.Sprintf("Not applicable to %s targets", target.Type)
+		c.State = NOTAPPLICABLE
+		logger.Warn("", zap.String("Reason", c.Reason))
+		return
+	}
 
-	out, errmsgs, c.State = runAuditCommands(*subCheck)
+	result, errmsgs, state := runAuditCommands(*subCheck)
+	out := result.Output
+	c.State = state
+
+	// Tests of the exit code can tell a missing command themselves
+	if result.CommandNotFound && !subCheck.Tests.UsesSources() {
+		c.Reason = "Command not found: " + strings.TrimSpace(orDefault(result.Stderr, errmsgs))
+		c.State = WARN
+		logger.Warn("", zap.String("Reason", c.Reason))
+		return
+	}
 
 	if errmsgs != "" {
 		logger.Info("", zap.String("errmsgs", errmsgs))
 		c.Reason = out
-		// Make output more readable
-		if (errmsgs == "exit status 127" || errmsgs == "exit status 1") && strings.HasSuffix(out, "not found\n") {
-			c.Reason = strings.Replace(c.Reason, "sh: 1:", "Command", -1)
-			logger.Warn("", zap.String("Reason", c.Reason))
-		}
 	}
 
 	if c.State != "" {
+		// Native audit types set a state when the audit itself could not be carried out
+		if c.Reason == "" {
+			c.Reason = errmsgs
+		}
 		return
 	}
 
-	finalOutput := subCheck.Tests.Execute(out, c.ID, c.IsMultiple)
+	finalOutput := executeTests(subCheck.Tests, subCheck.auditer, result, c.ID, c.IsMultiple)
 
 	if finalOutput != nil {
 		c.ActualValue = removeUnicodeChars(finalOutput.ActualResult)
@@ -242,7 +305,16 @@ func removeUnicodeChars(value string) string {
 }
 
 func runAudit(audit string) (output string, err error) {
-	var out bytes.Buffer
+	res, err := runAuditResult(defaultTarget, audit)
+	return res.Output, err
+}
+
+// shellCommandNotFound is the exit code of the shell when a command does not exist.
+const shellCommandNotFound = 127
+
+// runAuditResult runs the audit script with the shell of the target.
+func runAuditResult(target *Target, audit string) (result AuditResult, err error) {
+	var stdout, stderr bytes.Buffer
 
 	logger, err := log.ZapLogger(nil, nil)
 	if err != nil {
@@ -252,82 +324,74 @@ func runAudit(audit string) (output string, err error) {
 
 	audit = strings.TrimSpace(audit)
 	if len(audit) == 0 {
-		return output, err
+		return result, err
 	}
 
-	cmd := exec.Command("/bin/sh")
-	cmd.Stdin = strings.NewReader(audit)
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err = cmd.Run()
-	output = out.String()
+	combined := &lockedBuffer{}
+	start := time.Now()
+	err = target.run(context.Background(), targetCommand{
+		args:   []string{"/bin/sh"},
+		stdin:  strings.NewReader(audit),
+		stdout: io.MultiWriter(&stdout, combined),
+		stderr: io.MultiWriter(&stderr, combined),
+	})
+	result.Duration = time.Since(start)
+	result.Output = combined.buf.String()
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.code
+		result.CommandNotFound = result.ExitCode == shellCommandNotFound
+	}
 
 	if err != nil {
-		err = fmt.Errorf("failed to run: %q, output: %q, error: %s", audit, output, err)
+		err = fmt.Errorf("failed to run: %q, output: %q, error: %s", audit, result.Output, err)
 	} else {
 		logger.Warn("", zap.String("Command", audit))
-		logger.Warn("", zap.String("Output", output))
+		logger.Warn("", zap.String("Output", result.Output))
 	}
-	return output, err
+	return result, err
 }
 
-func runAuditCommands(c BaseCheck) (output, errMessage string, state State) {
+func runAuditCommands(c BaseCheck) (result AuditResult, errMessage string, state State) {
 
 	// If check type is manual, force result to WARN.
 	if c.Type == "manual" {
-		return output, errMessage, WARN
+		return result, errMessage, WARN
 	}
 
 	if c.Type == "skip" {
-		return output, errMessage, INFO
+		return result, errMessage, INFO
 	}
 	if c.auditer != nil {
 		if len(c.customConfigs) == 0 {
 			c.customConfigs = append(c.customConfigs, c.Audit)
 		}
-		return c.auditer.Execute(c.customConfigs...)
+		return executeAudit(c.auditer, c.customConfigs...)
 	}
 	return
 }
 
-func getFirstValidSubCheck(subChecks []*SubCheck, definedConstraints map[string][]string) (subCheck *BaseCheck) {
-	for _, sc := range subChecks {
-		isSubCheckOk := true
-
-		for testConstraintKey, testConstraintVals := range sc.Constraints {
-
-			isSubCheckOk = isSubCheckCompatible(testConstraintKey, testConstraintVals, definedConstraints)
-
-			// If the sub check is not compatible with the constraints, move to the next one
-			if !isSubCheckOk {
-				break
-			}
+// selectSubCheck returns the first sub check whose constraints match, and
+// explains why each sub check up to it was, or wasn't, selected.
+func selectSubCheck(subChecks []*SubCheck, definedConstraints map[string][]string) (subCheck *BaseCheck, selection []string) {
+	for i, sc := range subChecks {
+		ok, explanation := matchConstraints(sc.Constraints, definedConstraints)
+		result := "not selected"
+		if ok {
+			result = "selected"
 		}
-
-		if isSubCheckOk {
-			return &sc.BaseCheck
+		if len(explanation) == 0 {
+			explanation = []string{"no constraints"}
 		}
-	}
-
-	return nil
-}
+		selection = append(selection, fmt.Sprintf("sub check %d %s: %s", i+1, result, strings.Join(explanation, "; ")))
 
-func isSubCheckCompatible(testConstraintKey string, testConstraintVals []string, definedConstraints map[string][]string) bool {
-	definedConstraintsVals := definedConstraints[testConstraintKey]
-
-	// If the constraint's key is not defined - the check is not compatible
-	if !(len(definedConstraintsVals) > 0) {
-		return false
-	}
-
-	// For each constraint of the check under the specific key, check if its defined
-	for _, val := range testConstraintVals {
-		if contains(definedConstraintsVals, val) {
-			return true
+		if ok {
+			return &sc.BaseCheck, selection
 		}
 	}
 
-	return false
+	return nil, selection
 }
 
 func contains(arr []string, obj string) bool {
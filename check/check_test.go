@@ -78,7 +78,94 @@ func TestCheck_Run(t *testing.T) {
 	}
 }
 
-func TestGetFirstValidSubCheck(t *testing.T) {
+func TestCheck_RunCommandNotFound(t *testing.T) {
+	ts := new(auditeval.Tests)
+	if err := yaml.Unmarshal([]byte(def1), ts); err != nil {
+		t.Fatalf("error unmarshaling tests yaml %v", err)
+	}
+
+	c := Check{Scored: true, Tests: ts, auditer: Audit("unknown_command --root")}
+	c.Run(testDefinedConstraints)
+	assert.EqualValues(t, WARN, c.State)
+	assert.Contains(t, c.Reason, "Command not found: ")
+	assert.Contains(t, c.Reason, "unknown_command")
+
+	c = Check{Scored: true, Tests: ts, auditer: &ExecAudit{Args: []string{"unknown_command", "--root"}}}
+	c.Run(testDefinedConstraints)
+	assert.EqualValues(t, WARN, c.State)
+	assert.Contains(t, c.Reason, "Command not found: ")
+	assert.Contains(t, c.Reason, "unknown_command")
+
+	// Tests of the exit code are evaluated
+	const notInstalled = `
+test_items:
+- source: exit_code
+  compare:
+    op: eq
+    value: 127
+`
+	ts = new(auditeval.Tests)
+	if err := yaml.Unmarshal([]byte(notInstalled), ts); err != nil {
+		t.Fatalf("error unmarshaling tests yaml %v", err)
+	}
+	for _, audit := range []Auditer{Audit("unknown_command --root"), &ExecAudit{Args: []string{"unknown_command", "--root"}}} {
+		c = Check{Scored: true, Tests: ts, auditer: audit}
+		c.Run(testDefinedConstraints)
+		assert.EqualValues(t, PASS, c.State, "%#v", audit)
+	}
+}
+
+func TestCheck_RunSources(t *testing.T) {
+	const tests = `
+test_items:
+- source: exit_code
+  compare:
+    op: eq
+    value: 0
+- source: stderr
+  compare:
+    op: eq
+    value: ""
+`
+	ts := new(auditeval.Tests)
+	if err := yaml.Unmarshal([]byte(tests), ts); err != nil {
+		t.Fatalf("error unmarshaling tests yaml %v", err)
+	}
+
+	cases := []struct {
+		audit Auditer
+		want  State
+	}{
+		{audit: Audit("echo hello"), want: PASS},
+		{audit: Audit("echo hello; exit 2"), want: FAIL},
+		{audit: Audit("echo warning >&2"), want: FAIL},
+		{audit: &ExecAudit{Args: []string{"true"}}, want: PASS},
+		{audit: &ExecAudit{Args: []string{"false"}}, want: FAIL},
+	}
+	for i, tc := range cases {
+		c := Check{Scored: true, Tests: ts, auditer: tc.audit}
+		c.Run(testDefinedConstraints)
+		assert.Equal(t, tc.want, c.State, "case %d", i)
+	}
+}
+
+func TestAudit_ExecuteResult(t *testing.T) {
+	result, errMsg, state := Audit("echo out; echo err >&2; exit 3").ExecuteResult()
+	assert.NotEmpty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Contains(t, result.Output, "out\n")
+	assert.Contains(t, result.Output, "err\n")
+	assert.Equal(t, 3, result.ExitCode)
+	assert.False(t, result.CommandNotFound)
+
+	result, _, _ = Audit("unknown_command").ExecuteResult()
+	assert.Equal(t, 127, result.ExitCode)
+	assert.True(t, result.CommandNotFound)
+}
+
+func TestSelectSubCheck(t *testing.T) {
 	type TestCase struct {
 		SubChecks []*SubCheck
 		Expected  bool
@@ -202,7 +289,7 @@ func TestGetFirstValidSubCheck(t *testing.T) {
 	}
 
 	for ii, testCase := range testCases {
-		chosen := getFirstValidSubCheck(testCase.SubChecks, testDefinedConstraints)
+		chosen, _ := selectSubCheck(testCase.SubChecks, testDefinedConstraints)
 		if !testCase.Expected {
 			if chosen != nil {
 				t.Errorf("case %d didn't expect to find a matching case: %v\n", ii, chosen)
@@ -317,12 +404,12 @@ func TestRunAuditCommands(t *testing.T) {
 	}
 
 	for i, c := range cases {
-		output, errmsg, state := runAuditCommands(c.b)
+		result, errmsg, state := runAuditCommands(c.b)
 		if state != c.s {
 			t.Errorf("Test %d: expected state %s, got %s", i, c.s, state)
 		}
-		if strings.TrimSpace(output) != c.o {
-			t.Errorf("Test %d: expected output %s, got %s", i, c.o, output)
+		if strings.TrimSpace(result.Output) != c.o {
+			t.Errorf("Test %d: expected output %s, got %s", i, c.o, result.Output)
 		}
 		if (errmsg != "") && !c.err {
 			t.Errorf("Test %d: unexpected errmsg %s", i, errmsg)
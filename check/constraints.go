@@ -0,0 +1,314 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Constraints map a defined key to the values it must match, e.g.
+// platform: [ubuntu, debian]. Keys must all match, and a key matches when one
+// of its values does, and none of its negated values, e.g.
+// platform: ["!rhel", "!centos"] matches platforms other than rhel and centos.
+// A value is an expression:
+//
+//	ubuntu          a defined value is ubuntu
+//	~ubuntu-2[02]   a defined value matches the regular expression
+//	>=1.24, <1.28   a defined value is a version in the range
+//	!expression     no defined value matches the expression
+//
+// Versions are ordered like semantic versions, whatever the format of the
+// packages of the platform: 1.24 is 1.24.0, and 1.24.0-rc.1 is older than it.
+// Revisions such as those of kernels, 5.14.0-284.el9, are newer.
+//
+// The any and all keys hold key=expression entries, e.g. "version=>=1.24",
+// of which one, or all, must match. An entry of any can join several with &&.
+const (
+	constraintsAny = "any"
+	constraintsAll = "all"
+)
+
+// condition is a constraint on the values defined for a key.
+type condition struct {
+	key      string
+	negate   bool
+	value    string
+	regexp   *regexp.Regexp
+	versions versionConstraints
+}
+
+// parseCondition parses the expression a value of key must match.
+func parseCondition(key, expr string) (condition, error) {
+	c := condition{key: key}
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "!") {
+		c.negate = true
+		expr = strings.TrimSpace(expr[1:])
+	}
+	switch {
+	case expr == "":
+		return c, fmt.Errorf("empty constraint on %s", key)
+	case strings.HasPrefix(expr, "~"):
+		re, err := regexp.Compile(expr[1:])
+		if err != nil {
+			return c, fmt.Errorf("invalid constraint on %s: %v", key, err)
+		}
+		c.regexp = re
+	case strings.ContainsAny(expr[:1], "<>="):
+		versions, err := parseVersionConstraints(expr)
+		if err != nil {
+			return c, fmt.Errorf("invalid constraint on %s: %v", key, err)
+		}
+		for i := range versions {
+			versions[i].version = trimVersionPrefix(versions[i].version)
+		}
+		c.versions = versions
+	default:
+		c.value = expr
+	}
+	return c, nil
+}
+
+// matchValue tests a single defined value.
+func (c condition) matchValue(v string) bool {
+	switch {
+	case c.regexp != nil:
+		return c.regexp.MatchString(v)
+	case c.versions != nil:
+		return c.versions.match(versionSemver, trimVersionPrefix(v))
+	}
+	return v == c.value
+}
+
+// holds tests if a defined value of the key matches, or none does when the
+// condition is negated.
+func (c condition) holds(defined map[string][]string) bool {
+	for _, v := range defined[c.key] {
+		if c.matchValue(v) {
+			return !c.negate
+		}
+	}
+	return c.negate
+}
+
+func (c condition) String() string {
+	not := ""
+	if c.negate {
+		not = " not"
+	}
+	switch {
+	case c.regexp != nil:
+		if c.negate {
+			return fmt.Sprintf("%s does not match %s", c.key, c.regexp)
+		}
+		return fmt.Sprintf("%s matches %s", c.key, c.regexp)
+	case c.versions != nil:
+		ranges := make([]string, len(c.versions))
+		for i, vc := range c.versions {
+			ranges[i] = vc.op + " " + vc.version
+		}
+		return fmt.Sprintf("%s%s %s", c.key, not, strings.Join(ranges, ", "))
+	}
+	return fmt.Sprintf("%s is%s %s", c.key, not, c.value)
+}
+
+// trimVersionPrefix drops the v of versions like v1.26.1.
+func trimVersionPrefix(v string) string {
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') && isDigit(v[1]) {
+		return v[1:]
+	}
+	return v
+}
+
+// clause holds when all the conditions of one of its alternatives hold.
+type clause [][]condition
+
+func (cl clause) holds(defined map[string][]string) bool {
+	for _, alternative := range cl {
+		ok := true
+		for _, c := range alternative {
+			ok = ok && c.holds(defined)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// explain describes the clause, whether it holds, and the values defined for
+// its keys, e.g. "platform is rhel: not met, platform=ubuntu".
+func (cl clause) explain(defined map[string][]string) string {
+	var alternatives, values []string
+	seen := map[string]bool{}
+	for _, alternative := range cl {
+		conditions := make([]string, len(alternative))
+		for i, c := range alternative {
+			conditions[i] = c.String()
+			if seen[c.key] {
+				continue
+			}
+			seen[c.key] = true
+			if len(defined[c.key]) == 0 {
+				values = append(values, c.key+" not defined")
+			}
+			for _, v := range defined[c.key] {
+				values = append(values, c.key+"="+v)
+			}
+		}
+		alternatives = append(alternatives, strings.Join(conditions, " and "))
+	}
+	met := "not met"
+	if cl.holds(defined) {
+		met = "met"
+	}
+	return fmt.Sprintf("%s: %s, %s", strings.Join(alternatives, " or "), met, strings.Join(values, ", "))
+}
+
+// parseConstraints parses constraints into clauses which must all hold.
+func parseConstraints(constraints map[string][]string) ([]clause, error) {
+	keys := make([]string, 0, len(constraints))
+	for key := range constraints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var clauses []clause
+	for _, key := range keys {
+		switch key {
+		case constraintsAny:
+			var cl clause
+			for _, entry := range constraints[key] {
+				var alternative []condition
+				for _, part := range strings.Split(entry, "&&") {
+					c, err := parseEntry(part)
+					if err != nil {
+						return nil, err
+					}
+					alternative = append(alternative, c)
+				}
+				cl = append(cl, alternative)
+			}
+			clauses = append(clauses, cl)
+		case constraintsAll:
+			for _, entry := range constraints[key] {
+				c, err := parseEntry(entry)
+				if err != nil {
+					return nil, err
+				}
+				clauses = append(clauses, clause{{c}})
+			}
+		default:
+			var positives, negations []condition
+			for _, expr := range constraints[key] {
+				c, err := parseCondition(key, expr)
+				if err != nil {
+					return nil, err
+				}
+				if c.negate {
+					negations = append(negations, c)
+				} else {
+					positives = append(positives, c)
+				}
+			}
+			clauses = append(clauses, keyClause(positives, negations))
+		}
+	}
+	return clauses, nil
+}
+
+// keyClause holds when one of the positive conditions and all the negations
+// of a key hold.
+func keyClause(positives, negations []condition) clause {
+	if len(positives) == 0 {
+		return clause{negations}
+	}
+	cl := make(clause, len(positives))
+	for i, c := range positives {
+		cl[i] = append([]condition{c}, negations...)
+	}
+	return cl
+}
+
+// parseEntry parses a key=expression entry of any or all.
+func parseEntry(entry string) (condition, error) {
+	key, expr, found := strings.Cut(entry, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return condition{}, fmt.Errorf("invalid constraint %q, expected key=expression", strings.TrimSpace(entry))
+	}
+	return parseCondition(key, expr)
+}
+
+// matchConstraints tests constraints against the defined values, and explains
+// the result with a line per clause.
+func matchConstraints(constraints map[string][]string, defined map[string][]string) (bool, []string) {
+	clauses, err := parseConstraints(constraints)
+	if err != nil {
+		return false, []string{err.Error()}
+	}
+	ok := true
+	explanation := make([]string, 0, len(clauses))
+	for _, cl := range clauses {
+		ok = cl.holds(defined) && ok
+		explanation = append(explanation, cl.explain(defined))
+	}
+	return ok, explanation
+}
+
+// unmetConstraints returns the reason constraints are not met, e.g. "Group
+// constraints not met: platform is rhel: not met, platform=ubuntu", or an
+// empty string when they are.
+func unmetConstraints(scope string, constraints map[string][]string, defined map[string][]string) string {
+	if ok, explanation := matchConstraints(constraints, defined); !ok {
+		return scope + " constraints not met: " + strings.Join(explanation, "; ")
+	}
+	return ""
+}
+
+// checkConstraints verifies that the constraints of the controls, groups,
+// checks and sub checks parse.
+func (controls *Controls) checkConstraints() error {
+	if _, err := parseConstraints(controls.Constraints); err != nil {
+		return fmt.Errorf("controls: %v", err)
+	}
+	var err error
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
+		if err == nil {
+			err = group.checkConstraints()
+		}
+	})
+	return err
+}
+
+func (group *Group) checkConstraints() error {
+	if _, err := parseConstraints(group.Constraints); err != nil {
+		return fmt.Errorf("group %s: %v", group.ID, err)
+	}
+	for _, check := range group.Checks {
+		if _, err := parseConstraints(check.Constraints); err != nil {
+			return fmt.Errorf("check %s: %v", check.ID, err)
+		}
+		for _, subCheck := range check.SubChecks {
+			if _, err := parseConstraints(subCheck.Constraints); err != nil {
+				return fmt.Errorf("check %s: %v", check.ID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchConstraints(t *testing.T) {
+	defined := map[string][]string{
+		"platform": {"ubuntu"},
+		"os":       {"ubuntu-22.04"},
+		"version":  {"v1.26.3"},
+		"boot":     {"grub"},
+	}
+	cases := []struct {
+		constraints map[string][]string
+		expected    bool
+	}{
+		{constraints: nil, expected: true},
+		{constraints: map[string][]string{"platform": {"ubuntu"}}, expected: true},
+		{constraints: map[string][]string{"platform": {"rhel", "ubuntu"}, "boot": {"grub"}}, expected: true},
+		{constraints: map[string][]string{"platform": {"ubuntu"}, "boot": {"lilo"}}, expected: false},
+		{constraints: map[string][]string{"something": {"undefined"}}, expected: false},
+		{constraints: map[string][]string{"platform": {"!rhel"}}, expected: true},
+		{constraints: map[string][]string{"platform": {"!ubuntu"}}, expected: false},
+		{constraints: map[string][]string{"something": {"!undefined"}}, expected: true},
+		{constraints: map[string][]string{"os": {"~ubuntu-2[02]"}}, expected: true},
+		{constraints: map[string][]string{"os": {"~^debian"}}, expected: false},
+		{constraints: map[string][]string{"os": {"!~^debian"}}, expected: true},
+		{constraints: map[string][]string{"version": {">=1.24"}}, expected: true},
+		{constraints: map[string][]string{"version": {">= 1.24, < 1.26"}}, expected: false},
+		{constraints: map[string][]string{"version": {">=v1.26, <1.27"}}, expected: true},
+		{constraints: map[string][]string{"version": {"!=1.26.3"}}, expected: false},
+		{constraints: map[string][]string{"version": {"=1.26.3.0"}}, expected: true},
+		{constraints: map[string][]string{"version": {">=1.26.3-rc.1, <1.26.3"}}, expected: false},
+		{constraints: map[string][]string{"platform": {"!rhel", "!centos"}}, expected: true},
+		{constraints: map[string][]string{"platform": {"!rhel", "!ubuntu"}}, expected: false},
+		{constraints: map[string][]string{"platform": {"debian", "ubuntu", "!ubuntu"}}, expected: false},
+		{constraints: map[string][]string{"os": {"~^ubuntu", "!ubuntu-20.04"}}, expected: true},
+		{constraints: map[string][]string{"any": {"platform=rhel", "version=>=1.25"}}, expected: true},
+		{constraints: map[string][]string{"any": {"platform=rhel && version=>=8", "os=~^debian"}}, expected: false},
+		{constraints: map[string][]string{"any": {"platform=ubuntu && version=>=1.20"}}, expected: true},
+		{constraints: map[string][]string{"all": {"version=>=1.20", "version=!~-rc"}}, expected: true},
+		{constraints: map[string][]string{"all": {"platform=ubuntu", "boot=lilo"}}, expected: false},
+	}
+	for _, c := range cases {
+		ok, _ := matchConstraints(c.constraints, defined)
+		assert.Equal(t, c.expected, ok, "%v", c.constraints)
+	}
+}
+
+func TestMatchConstraints_Explanation(t *testing.T) {
+	defined := map[string][]string{"platform": {"ubuntu"}, "version": {"1.26"}}
+
+	ok, explanation := matchConstraints(map[string][]string{
+		"platform": {"rhel", "!ubuntu"},
+		"version":  {">=1.24, <1.28"},
+		"any":      {"os=~^debian && platform=debian", "version=1.26"},
+	}, defined)
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"os matches ^debian and platform is debian or version is 1.26: met, os not defined, platform=ubuntu, version=1.26",
+		"platform is rhel and platform is not ubuntu: not met, platform=ubuntu",
+		"version >= 1.24, < 1.28: met, version=1.26",
+	}, explanation)
+}
+
+func TestParseConstraints_Errors(t *testing.T) {
+	cases := map[string]map[string][]string{
+		`invalid constraint on os: error parsing regexp: missing closing ]: ` + "`[02`": {"os": {"~ubuntu-2[02"}},
+		`empty constraint on platform`:                                   {"platform": {"!"}},
+		`invalid constraint on version: invalid version constraint ">="`: {"version": {">="}},
+		`invalid constraint "platform", expected key=expression`:         {"any": {"platform"}},
+	}
+	for expected, constraints := range cases {
+		_, err := parseConstraints(constraints)
+		assert.EqualError(t, err, expected)
+	}
+}
+
+const constraintsControls = `---
+controls:
+id: 1
+text: "Constraints"
+groups:
+- id: 1.1
+  text: "Kubernetes"
+  constraints:
+    version: [">=1.24"]
+  checks:
+    - id: 1.1.1
+      text: "Ensure the right sub check is selected"
+      sub_checks:
+      - check:
+          constraints:
+            platform: ["!ubuntu"]
+          audit: "echo other"
+          tests:
+            test_items:
+            - flag: "other"
+      - check:
+          constraints:
+            any: ["platform=rhel", "os=~ubuntu-2[02]"]
+          audit: "echo ubuntu"
+          tests:
+            test_items:
+            - flag: "ubuntu"
+      scored: true
+- id: 1.2
+  text: "Legacy"
+  constraints:
+    version: ["<1.20"]
+  checks:
+    - id: 1.2.1
+      text: "Ensure legacy settings"
+      audit: "echo ok"
+`
+
+func TestControls_RunGroupConstraints(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(constraintsControls), []string{"platform=ubuntu", "os=ubuntu-22.04", "version=1.26.1"})
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	summary := controls.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Info: 1}, summary)
+
+	selected := controls.Groups[0].Checks[0]
+	assert.EqualValues(t, PASS, selected.State)
+	assert.Equal(t, []string{
+		"sub check 1 not selected: platform is not ubuntu: not met, platform=ubuntu",
+		"sub check 2 selected: platform is rhel or os matches ubuntu-2[02]: met, platform=ubuntu, os=ubuntu-22.04",
+	}, selected.Selection)
+
+	skipped := controls.Groups[1].Checks[0]
+	assert.EqualValues(t, INFO, skipped.State)
+	assert.Equal(t, "Group constraints not met: version < 1.20: not met, version=1.26.1", skipped.Reason)
+}
+
+func TestControls_InvalidConstraints(t *testing.T) {
+	_, err := NewBench().NewControls([]byte(`---
+controls:
+groups:
+- id: 1.1
+  checks:
+    - id: 1.1.1
+      sub_checks:
+      - check:
+          constraints:
+            version: [">="]
+`), nil)
+	assert.EqualError(t, err, `check 1.1.1: invalid constraint on version: invalid version constraint ">="`)
+}
+
+const checkConstraintsControls = `---
+controls:
+id: 1
+text: "Constraints"
+constraints:
+  platform: [ubuntu, debian]
+groups:
+- id: 1.1
+  text: "Platform"
+  checks:
+    - id: 1.1.1
+      text: "Ensure the check runs on ubuntu"
+      constraints:
+        platform: [ubuntu]
+        version: [">=1.24"]
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+    - id: 1.1.2
+      text: "Ensure the check runs on debian"
+      type: manual
+      constraints:
+        platform: [debian]
+      audit: "echo ok"
+      scored: true
+`
+
+func TestControls_RunGroupCheckConstraints(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(checkConstraintsControls), []string{"platform=ubuntu", "version=1.26"})
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Equal(t, Summary{Pass: 1, Info: 1}, controls.RunGroup())
+	assert.Empty(t, controls.Reason)
+	assert.Equal(t, "Check constraints not met: platform is debian: not met, platform=ubuntu", controls.Groups[0].Checks[1].Reason)
+}
+
+func TestControls_RunControlsConstraints(t *testing.T) {
+	for _, run := range []func(*Controls) Summary{
+		func(c *Controls) Summary { return c.RunGroup() },
+		func(c *Controls) Summary { return c.RunChecks("1.1.1") },
+	} {
+		controls, err := NewBench().NewControls([]byte(checkConstraintsControls), []string{"platform=rhel"})
+		if err != nil {
+			t.Fatalf("failed to load controls: %v", err)
+		}
+		summary := run(controls)
+		assert.Equal(t, 0, summary.Pass)
+		assert.NotZero(t, summary.Info)
+
+		reason := "Controls constraints not met: platform is ubuntu or platform is debian: not met, platform=rhel"
+		assert.Equal(t, reason, controls.Reason)
+		for _, check := range controls.Groups[0].Checks {
+			assert.EqualValues(t, INFO, check.State)
+			assert.Equal(t, reason, check.Reason)
+		}
+	}
+}
+
+func TestControls_InvalidControlsConstraints(t *testing.T) {
+	_, err := NewBench().NewControls([]byte(`---
+controls:
+constraints:
+  os: ["~["]
+groups:
+- id: 1.1
+`), nil)
+	assert.EqualError(t, err, "controls: invalid constraint on os: error parsing regexp: missing closing ]: `[`")
+}
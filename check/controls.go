@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"strings"
+
 	"github.com/aquasecurity/bench-common/log"
 	"go.uber.org/zap"
 
@@ -36,9 +38,18 @@ type Controls struct {
 	Description string   `json:"text"`
 	Text        string   `json:"-"`
 	Groups      []*Group `json:"tests" yaml:"groups"`
+	// Constraints restrict the whole controls, e.g. to a platform.
+	Constraints map[string][]string `yaml:"constraints" json:"constraints,omitempty"`
+	// Reason is why the checks of the controls were skipped.
+	Reason string `json:"reason,omitempty" yaml:"-"`
 	Summary
 	DefinedConstraints map[string][]string
-	customConfigs      []interface{}
+	// Detected are the constraints detected from the target, when detection
+	// was enabled with a Detect custom config.
+	Detected map[string][]string `json:"detected,omitempty" yaml:"-"`
+	// Target is the target the checks ran against, when one was configured.
+	Target        *TargetMetadata `json:"target,omitempty" yaml:"-"`
+	customConfigs []interface{}
 }
 
 // Summary is a summary of the results of control checks run.
@@ -47,6 +58,8 @@ type Summary struct {
 	Fail int `json:"total_fail"`
 	Warn int `json:"total_warn"`
 	Info int `json:"total_info"`
+	// NotApplicable counts the checks which don't apply to the system.
+	NotApplicable int `json:"total_not_applicable"`
 }
 
 var defaultBench bench // for backward compatibility
@@ -55,96 +68,215 @@ func NewControls(in []byte, definitions []string) (*Controls, error) {
 	return defaultBench.NewControls(in, definitions)
 }
 
-// RunGroup runs all checks in a group.
+// RunGroup runs all checks in a group, and in its sub groups. Checks of other
+// groups which the checks depend on are run first, and reported in a group
+// of their own.
 func (controls *Controls) RunGroup(gids ...string) Summary {
 	g := []*Group{}
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Summary.Info = 0, 0, 0, 0
+	controls.Summary = Summary{}
+	controls.Reason = unmetConstraints("Controls", controls.Constraints, controls.DefinedConstraints)
 	// If no group id is passed run all group checks.
 	if len(gids) == 0 {
 		gids = controls.getAllGroupIDs()
 	}
 
-	for _, group := range controls.Groups {
-		for _, gid := range gids {
-			if gid == group.ID {
-				for _, check := range group.Checks {
-					// Check if group has constraints
-					if group.Constraints != nil {
-						groupConstraintsOk := true
-						for testConstraintKey, testConstraintVals := range group.Constraints {
-							groupConstraintsOk = isSubCheckCompatible(testConstraintKey, testConstraintVals, controls.DefinedConstraints)
-							// If group constraints is not applied then skip test.
-							if !groupConstraintsOk {
-								check.Type = SKIP
-							}
-						}
-					}
-					if group.Type == SKIP {
-						check.Type = SKIP
-					}
-					check.Run(controls.DefinedConstraints)
-					check.TestInfo = append(check.TestInfo, check.Remediation)
-					summarize(controls, check)
-					summarizeGroup(group, check)
-				}
-
-				g = append(g, group)
+	// A check is selected by the ID of its group, or of one of its ancestors
+	selected := map[*Check]bool{}
+	pathOf := map[*Check][]*Group{}
+	WalkGroups(controls.Groups, func(group *Group, ancestors []*Group) {
+		path := append(ancestors[:len(ancestors):len(ancestors)], group)
+		for _, check := range group.Checks {
+			pathOf[check] = path
+			if groupSelected(path, gids) {
+				selected[check] = true
 			}
 		}
-
+	})
+
+	checks := controls.checksByID()
+	ran := map[*Check]bool{}
+	for _, check := range controls.runOrder(selected) {
+		path := pathOf[check]
+		// The constraints and type of a group apply to its sub groups too
+		for _, group := range path {
+			// If group constraints is not applied then skip test.
+			if reason := unmetConstraints("Group", group.Constraints, controls.DefinedConstraints); reason != "" && check.Type != SKIP {
+				check.Type = SKIP
+				check.skipReason = reason
+			}
+			if group.Type == SKIP {
+				check.Type = SKIP
+			}
+		}
+		controls.runCheck(check, path, checks)
+		summarize(controls, check)
+		if selected[check] {
+			// Counters roll up to the ancestors of the group
+			for _, group := range path {
+				summarizeGroup(group, check)
+			}
+		}
+		ran[check] = true
 	}
 
+	WalkGroups(controls.Groups, func(group *Group, ancestors []*Group) {
+		if groupSelected(ancestors, gids) {
+			// Reported with its ancestor
+			return
+		}
+		if contains(gids, group.ID) {
+			g = append(g, group)
+			return
+		}
+		// Prerequisites of the selected checks
+		var w *Group
+		for _, check := range group.Checks {
+			if ran[check] {
+				if w == nil {
+					w = &Group{ID: group.ID, Description: group.Description, Text: group.Text, Checks: []*Check{}}
+					g = append(g, w)
+				}
+				w.Checks = append(w.Checks, check)
+				summarizeGroup(w, check)
+			}
+		}
+	})
+
 	controls.Groups = g
 	return controls.Summary
 }
 
-// RunChecks runs the checks with the supplied IDs.
+// RunChecks runs the checks with the supplied IDs, and the checks they
+// depend on.
 func (controls *Controls) RunChecks(ids ...string) Summary {
 	g := []*Group{}
-	m := make(map[string]*Group)
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Summary.Info = 0, 0, 0, 0
+	m := make(map[*Group]*Group)
+	controls.Summary = Summary{}
+	controls.Reason = unmetConstraints("Controls", controls.Constraints, controls.DefinedConstraints)
 
 	// If no groupid is passed run all group checks.
 	if len(ids) == 0 {
 		ids = controls.getAllCheckIDs()
 	}
 
-	for _, group := range controls.Groups {
+	selected := map[*Check]bool{}
+	pathOf := map[*Check][]*Group{}
+	WalkGroups(controls.Groups, func(group *Group, ancestors []*Group) {
+		path := append(ancestors[:len(ancestors):len(ancestors)], group)
+		for _, check := range group.Checks {
+			pathOf[check] = path
+			if contains(ids, check.ID) {
+				selected[check] = true
+			}
+		}
+	})
+
+	checks := controls.checksByID()
+	ran := map[*Check]bool{}
+	for _, check := range controls.runOrder(selected) {
+		controls.runCheck(check, pathOf[check], checks)
+		summarize(controls, check)
+		ran[check] = true
+	}
+
+	WalkGroups(controls.Groups, func(group *Group, ancestors []*Group) {
 		for _, check := range group.Checks {
-			for _, id := range ids {
-				if id == check.ID {
-					check.Run(controls.DefinedConstraints)
-					check.TestInfo = append(check.TestInfo, check.Remediation)
-					summarize(controls, check)
-
-					// Check if we have already added this checks group.
-					if v, ok := m[group.ID]; !ok {
-						// Create a group with same info
-						w := &Group{
-							ID:          group.ID,
-							Description: group.Description,
-							Checks:      []*Check{},
-						}
-
-						// Add this check to the new group
-						w.Checks = append(w.Checks, check)
-
-						// Add to groups we have visited.
-						m[w.ID] = w
+			if !ran[check] {
+				continue
+			}
+			// Copy the groups from the top level down to the check, unless
+			// we have already added them.
+			var parent *Group
+			for _, original := range pathOf[check] {
+				w, ok := m[original]
+				if !ok {
+					// Create a group with same info
+					w = &Group{
+						ID:          original.ID,
+						Description: original.Description,
+						Checks:      []*Check{},
+					}
+					m[original] = w
+					if parent == nil {
 						g = append(g, w)
 					} else {
-						v.Checks = append(v.Checks, check)
+						parent.Groups = append(parent.Groups, w)
 					}
-
 				}
+				summarizeGroup(w, check)
+				parent = w
 			}
+
+			// Add this check to the new group
+			parent.Checks = append(parent.Checks, check)
 		}
-	}
+	})
 
 	controls.Groups = g
 	return controls.Summary
 }
 
+// runCheck runs check. A check of controls whose constraints are not met is
+// skipped, a check of a group which does not apply to the system is
+// NOT_APPLICABLE, and a check one of whose dependencies did not end in a
+// required state is INFO. path holds the groups from the top level down to the
+// group of the check.
+func (controls *Controls) runCheck(check *Check, path []*Group, checks map[string]*Check) {
+	if controls.Reason != "" {
+		check.Type = SKIP
+		check.skipReason = controls.Reason
+	}
+	reason := check.unmetDependency(checks)
+	switch {
+	case check.Type == SKIP:
+		check.Run(controls.DefinedConstraints)
+	case notApplicable(path) != "":
+		check.Reason = notApplicable(path)
+		check.State = NOTAPPLICABLE
+	case reason != "":
+		check.Reason = reason
+		check.State = INFO
+	default:
+		check.Run(controls.DefinedConstraints)
+	}
+	check.TestInfo = append(check.TestInfo, check.Remediation)
+}
+
+// notApplicable returns the reason the first of groups which does not apply
+// to the system doesn't, as a group applies only if its ancestors do.
+func notApplicable(groups []*Group) string {
+	for _, group := range groups {
+		if reason := group.notApplicable(); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// WalkGroups calls fn for each of groups, then for each of its sub groups,
+// with the ancestors of the group from the top level down.
+func WalkGroups(groups []*Group, fn func(group *Group, ancestors []*Group)) {
+	walkGroups(groups, nil, fn)
+}
+
+func walkGroups(groups []*Group, ancestors []*Group, fn func(group *Group, ancestors []*Group)) {
+	for _, group := range groups {
+		fn(group, ancestors)
+		// Full slice expression so that siblings don't share the slice
+		walkGroups(group.Groups, append(ancestors[:len(ancestors):len(ancestors)], group), fn)
+	}
+}
+
+// groupSelected tests if the ID of one of the groups is in gids.
+func groupSelected(groups []*Group, gids []string) bool {
+	for _, group := range groups {
+		if contains(gids, group.ID) {
+			return true
+		}
+	}
+	return false
+}
+
 func (controls *Controls) getAllGroupIDs() []string {
 	var ids []string
 
@@ -157,11 +289,11 @@ func (controls *Controls) getAllGroupIDs() []string {
 func (controls *Controls) getAllCheckIDs() []string {
 	var ids []string
 
-	for _, group := range controls.Groups {
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
 		for _, check := range group.Checks {
 			ids = append(ids, check.ID)
 		}
-	}
+	})
 	return ids
 
 }
@@ -173,10 +305,25 @@ func (controls *Controls) JSON() ([]byte, error) {
 
 // JUnit encodes the results of last run to JUnit.
 func (controls *Controls) JUnit() ([]byte, error) {
+	suite := controls.junitSuite()
+
+	var b bytes.Buffer
+	encoder := xml.NewEncoder(&b)
+	encoder.Indent("", "    ")
+	err := encoder.Encode(suite)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate JUnit report: %s", err.Error())
+	}
+
+	return b.Bytes(), nil
+}
+
+// junitSuite returns the results of last run as a JUnit test suite.
+func (controls *Controls) junitSuite() reporters.JUnitTestSuite {
 	suite := reporters.JUnitTestSuite{
 		Name:      controls.Description,
 		TestCases: []reporters.JUnitTestCase{},
-		Tests:     controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn,
+		Tests:     controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn + controls.Summary.NotApplicable,
 		Failures:  controls.Summary.Fail,
 	}
 
@@ -186,7 +333,16 @@ func (controls *Controls) JUnit() ([]byte, error) {
 	}
 	defer logger.Sync() // nolint: errcheck
 
-	for _, g := range controls.Groups {
+	WalkGroups(controls.Groups, func(g *Group, ancestors []*Group) {
+		// The class of the checks of a sub group is the path to it
+		className := g.Description
+		if len(ancestors) > 0 {
+			names := make([]string, 0, len(ancestors)+1)
+			for _, ancestor := range ancestors {
+				names = append(names, ancestor.Description)
+			}
+			className = strings.Join(append(names, g.Description), " / ")
+		}
 		for _, check := range g.Checks {
 			jsonCheck := ""
 			jsonBytes, err := json.Marshal(check)
@@ -197,7 +353,7 @@ func (controls *Controls) JUnit() ([]byte, error) {
 			}
 			tc := reporters.JUnitTestCase{
 				Name:      fmt.Sprintf("%v %v", check.ID, check.Description),
-				ClassName: g.Description,
+				ClassName: className,
 
 				// Store the entire json serialization as system out so we don't lose data in cases where deeper debugging is necessary.
 				SystemOut: jsonCheck,
@@ -210,6 +366,8 @@ func (controls *Controls) JUnit() ([]byte, error) {
 				// WARN and INFO are two different versions of skipped tests. Either way it would be a false positive/negative to report
 				// it any other way.
 				tc.Skipped = &reporters.JUnitSkipped{}
+			case NOTAPPLICABLE:
+				tc.Skipped = &reporters.JUnitSkipped{Message: check.Reason}
 			case PASS:
 			default:
 				logger.Warn("", zap.String("Unrecognized state", string(check.State)))
@@ -217,30 +375,12 @@ func (controls *Controls) JUnit() ([]byte, error) {
 
 			suite.TestCases = append(suite.TestCases, tc)
 		}
-	}
-
-	var b bytes.Buffer
-	encoder := xml.NewEncoder(&b)
-	encoder.Indent("", "    ")
-	err = encoder.Encode(suite)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to generate JUnit report: %s", err.Error())
-	}
-
-	return b.Bytes(), nil
+	})
+	return suite
 }
 
 func summarize(controls *Controls, check *Check) {
-	switch check.State {
-	case PASS:
-		controls.Summary.Pass++
-	case FAIL:
-		controls.Summary.Fail++
-	case WARN:
-		controls.Summary.Warn++
-	case INFO:
-		controls.Summary.Info++
-	}
+	controls.Summary.add(check.State)
 }
 
 func summarizeGroup(group *Group, check *Check) {
@@ -253,5 +393,7 @@ func summarizeGroup(group *Group, check *Check) {
 		group.Warn++
 	case INFO:
 		group.Info++
+	case NOTAPPLICABLE:
+		group.NotApplicable++
 	}
 }
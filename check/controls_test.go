@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/onsi/ginkgo/reporters"
+	"github.com/stretchr/testify/assert"
 )
 
 const def = `---
@@ -348,3 +349,157 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 		})
 	}
 }
+
+const nestedControls = `---
+controls:
+id: 1
+text: "Nested"
+groups:
+- id: 1
+  description: "Control Plane"
+  constraints:
+    platform: [ubuntu]
+  groups:
+  - id: 1.1
+    description: "API Server"
+    checks:
+      - id: 1.1.1
+        text: "Ensure the API server passes"
+        audit: "echo ok"
+        tests:
+          test_items:
+          - flag: "ok"
+        scored: true
+    groups:
+    - id: 1.1.1.a
+      description: "Admission"
+      checks:
+        - id: 1.1.1.a.1
+          text: "Ensure the admission plugin fails"
+          audit: "echo ko"
+          tests:
+            test_items:
+            - flag: "ok"
+          scored: true
+    - id: 1.1.1.b
+      description: "Legacy"
+      type: skip
+      checks:
+        - id: 1.1.1.b.1
+          text: "Ensure the legacy plugin is skipped"
+          audit: "echo ok"
+  - id: 1.2
+    description: "Scheduler"
+    constraints:
+      boot: [lilo]
+    groups:
+    - id: 1.2.1
+      description: "Profiles"
+      checks:
+        - id: 1.2.1.1
+          text: "Ensure the profile is skipped"
+          audit: "echo ok"
+- id: 2
+  description: "Other Platform"
+  constraints:
+    platform: [debian]
+  groups:
+  - id: 2.1
+    description: "Nodes"
+    checks:
+      - id: 2.1.1
+        text: "Ensure the node is skipped"
+        audit: "echo ok"
+        tests:
+          test_items:
+          - flag: "ok"
+`
+
+func TestControls_RunGroupNested(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(nestedControls), definedTestConstraints)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	checks := controls.checksByID()
+	assert.Equal(t, Summary{Pass: 1, Fail: 1, Info: 3}, controls.RunGroup())
+
+	// Skips and constraints are inherited
+	assert.EqualValues(t, INFO, checks["1.1.1.b.1"].State)
+	assert.Equal(t, "Test marked as skip", checks["1.1.1.b.1"].Reason)
+	assert.Equal(t, "Group constraints not met: boot is lilo: not met, boot=grub", checks["1.2.1.1"].Reason)
+	assert.Equal(t, "Group constraints not met: platform is debian: not met, platform=ubuntu, platform=rhel", checks["2.1.1"].Reason)
+
+	// Counters roll up to every level
+	root := controls.Groups[0]
+	assert.Equal(t, []int{1, 1, 2}, []int{root.Pass, root.Fail, root.Info})
+	apiServer := root.Groups[0]
+	assert.Equal(t, []int{1, 1, 1}, []int{apiServer.Pass, apiServer.Fail, apiServer.Info})
+	assert.Equal(t, 1, apiServer.Groups[0].Fail)
+	assert.Equal(t, 1, root.Groups[1].Groups[0].Info)
+	assert.Equal(t, 1, controls.Groups[1].Info)
+
+	// Sub groups are in the JSON output
+	out, err := controls.JSON()
+	assert.NoError(t, err)
+	var tree struct {
+		Tests []struct {
+			ID     string `json:"section"`
+			Groups []struct {
+				ID      string `json:"section"`
+				Results []struct {
+					ID string `json:"test_number"`
+				} `json:"results"`
+			} `json:"groups"`
+		} `json:"tests"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &tree))
+	assert.Equal(t, "1", tree.Tests[0].ID)
+	assert.Equal(t, "1.1", tree.Tests[0].Groups[0].ID)
+	assert.Equal(t, "1.1.1", tree.Tests[0].Groups[0].Results[0].ID)
+
+	// The class of a JUnit test case is the path to its group
+	classes := map[string]string{}
+	for _, tc := range controls.junitSuite().TestCases {
+		classes[strings.Fields(tc.Name)[0]] = tc.ClassName
+	}
+	assert.Equal(t, "Control Plane / API Server", classes["1.1.1"])
+	assert.Equal(t, "Control Plane / API Server / Admission", classes["1.1.1.a.1"])
+	assert.Equal(t, "Other Platform / Nodes", classes["2.1.1"])
+}
+
+func TestControls_RunGroupNestedSelection(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(nestedControls), definedTestConstraints)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	// A sub group is selected with its ancestor, and reported once
+	assert.Equal(t, Summary{Pass: 1, Fail: 1, Info: 1}, controls.RunGroup("1.1", "1.1.1.a"))
+	assert.Len(t, controls.Groups, 1)
+	assert.Equal(t, "1.1", controls.Groups[0].ID)
+	assert.Len(t, controls.Groups[0].Groups, 2)
+
+	controls, err = NewBench().NewControls([]byte(nestedControls), definedTestConstraints)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Equal(t, Summary{Fail: 1}, controls.RunGroup("1.1.1.a"))
+}
+
+func TestControls_RunChecksNested(t *testing.T) {
+	controls, err := NewBench().NewControls([]byte(nestedControls), definedTestConstraints)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, controls.RunChecks("1.1.1.a.1", "1.1.1"))
+
+	// The checks are reported in a copy of their groups
+	assert.Len(t, controls.Groups, 1)
+	root := controls.Groups[0]
+	assert.Equal(t, "1", root.ID)
+	assert.Empty(t, root.Checks)
+	assert.Equal(t, []int{1, 1}, []int{root.Pass, root.Fail})
+	assert.Len(t, root.Groups, 1)
+	assert.Equal(t, "1.1.1", root.Groups[0].Checks[0].ID)
+	assert.Len(t, root.Groups[0].Groups, 1)
+	assert.Equal(t, "1.1.1.a.1", root.Groups[0].Groups[0].Checks[0].ID)
+}
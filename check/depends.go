@@ -0,0 +1,178 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dependency is a check which must end in one of States for a check depending
+// on it to run, e.g. a check of the TLS certificate permissions only runs when
+// the check that TLS is enabled passes.
+type Dependency struct {
+	Check string `yaml:"check" json:"check"`
+	// States are the states the check must end in, PASS by default.
+	States []State `yaml:"states" json:"states,omitempty"`
+}
+
+// UnmarshalYAML accepts the ID of a check as a dependency on it passing.
+func (d *Dependency) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		d.Check = value.Value
+		return nil
+	}
+	type plain Dependency
+	return value.Decode((*plain)(d))
+}
+
+func (d Dependency) states() []State {
+	if len(d.States) == 0 {
+		return []State{PASS}
+	}
+	return d.States
+}
+
+// checksByID indexes the checks of controls by ID. The first of checks sharing
+// an ID is kept.
+func (controls *Controls) checksByID() map[string]*Check {
+	checks := map[string]*Check{}
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
+		for _, check := range group.Checks {
+			if _, ok := checks[check.ID]; !ok {
+				checks[check.ID] = check
+			}
+		}
+	})
+	return checks
+}
+
+// checkDependencies verifies that the dependencies of each check exist, with
+// known states, and have no cycle.
+func (controls *Controls) checkDependencies() error {
+	checks := controls.checksByID()
+	for _, check := range checks {
+		for _, dep := range check.DependsOn {
+			if _, ok := checks[dep.Check]; !ok {
+				return fmt.Errorf("check %s depends on unknown check %q", check.ID, dep.Check)
+			}
+			for _, state := range dep.states() {
+				switch state {
+				case PASS, FAIL, WARN, INFO, NOTAPPLICABLE:
+				default:
+					return fmt.Errorf("check %s depends on check %s with unknown state %q", check.ID, dep.Check, state)
+				}
+			}
+		}
+	}
+
+	// Depth first search, a check found again while its dependencies are
+	// being visited closes a cycle
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := map[string]int{}
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch marks[id] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != id {
+				start++
+			}
+			return fmt.Errorf("checks depend on each other: %s", strings.Join(append(path[start:], id), " -> "))
+		}
+		marks[id] = visiting
+		path = append(path, id)
+		for _, dep := range checks[id].DependsOn {
+			if err := visit(dep.Check); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[id] = visited
+		return nil
+	}
+	var err error
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
+		for _, check := range group.Checks {
+			if err == nil {
+				err = visit(check.ID)
+			}
+		}
+	})
+	return err
+}
+
+// runOrder returns the selected checks and the checks they depend on, in the
+// order of the controls, except that each check comes after its
+// dependencies.
+func (controls *Controls) runOrder(selected map[*Check]bool) []*Check {
+	checks := controls.checksByID()
+	var order []*Check
+	added := map[*Check]bool{}
+	var add func(check *Check)
+	add = func(check *Check) {
+		if added[check] {
+			return
+		}
+		added[check] = true
+		for _, dep := range check.DependsOn {
+			if prerequisite, ok := checks[dep.Check]; ok {
+				add(prerequisite)
+			}
+		}
+		order = append(order, check)
+	}
+	WalkGroups(controls.Groups, func(group *Group, _ []*Group) {
+		for _, check := range group.Checks {
+			if selected[check] {
+				add(check)
+			}
+		}
+	})
+	return order
+}
+
+// unmetDependency returns the reason a check can't run, when one of the
+// checks it depends on did not end in a required state.
+func (c *Check) unmetDependency(checks map[string]*Check) string {
+	for _, dep := range c.DependsOn {
+		prerequisite, ok := checks[dep.Check]
+		if !ok {
+			continue
+		}
+		states := dep.states()
+		met := false
+		for _, state := range states {
+			met = met || prerequisite.State == state
+		}
+		if !met {
+			required := make([]string, len(states))
+			for i, state := range states {
+				required[i] = string(state)
+			}
+			return fmt.Sprintf("Depends on check %s, which is %s instead of %s",
+				dep.Check, orDefault(string(prerequisite.State), "not run"), strings.Join(required, " or "))
+		}
+	}
+	return ""
+}
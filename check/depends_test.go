@@ -0,0 +1,144 @@
+package check
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dependsControls = `---
+controls:
+id: 1
+text: "Docker"
+groups:
+- id: 1.1
+  text: "TLS"
+  checks:
+    - id: 1.1.2
+      text: "Ensure the TLS certificate is not world readable"
+      audit: "echo 600"
+      depends_on:
+      - 1.1.1
+      tests:
+        test_items:
+        - flag: "600"
+      scored: true
+    - id: 1.1.3
+      text: "Ensure the daemon is reachable over TLS"
+      audit: "echo ok"
+      depends_on:
+      - check: 1.1.1
+        states: [FAIL, WARN]
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+- id: 1.2
+  text: "Daemon"
+  checks:
+    - id: 1.1.1
+      text: "Ensure TLS is enabled"
+      audit: "echo %s"
+      tests:
+        test_items:
+        - flag: "tlsverify"
+      scored: true
+`
+
+func newDependsControls(t *testing.T, flag string) *Controls {
+	t.Helper()
+	controls, err := NewBench().NewControls([]byte(fmt.Sprintf(dependsControls, flag)), nil)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	return controls
+}
+
+func TestDependency_UnmarshalYAML(t *testing.T) {
+	controls := newDependsControls(t, "tlsverify")
+	checks := controls.checksByID()
+	assert.Equal(t, []Dependency{{Check: "1.1.1"}}, checks["1.1.2"].DependsOn)
+	assert.Equal(t, []Dependency{{Check: "1.1.1", States: []State{FAIL, WARN}}}, checks["1.1.3"].DependsOn)
+}
+
+func TestControls_RunGroupDependsOn(t *testing.T) {
+	controls := newDependsControls(t, "tlsverify")
+	checks := controls.checksByID()
+	summary := controls.RunGroup("1.1")
+
+	assert.EqualValues(t, PASS, checks["1.1.1"].State)
+	assert.EqualValues(t, PASS, checks["1.1.2"].State)
+	assert.EqualValues(t, INFO, checks["1.1.3"].State)
+	assert.Equal(t, "Depends on check 1.1.1, which is PASS instead of FAIL or WARN", checks["1.1.3"].Reason)
+	assert.Equal(t, Summary{Pass: 2, Info: 1}, summary)
+
+	// The prerequisite is reported in a group of its own
+	if assert.Len(t, controls.Groups, 2) {
+		assert.Equal(t, "1.1", controls.Groups[0].ID)
+		assert.Equal(t, 1, controls.Groups[0].Pass)
+		assert.Equal(t, 1, controls.Groups[0].Info)
+		assert.Equal(t, "1.2", controls.Groups[1].ID)
+		assert.Equal(t, 1, controls.Groups[1].Pass)
+	}
+}
+
+func TestControls_RunChecksDependsOn(t *testing.T) {
+	controls := newDependsControls(t, "disabled")
+	checks := controls.checksByID()
+	summary := controls.RunChecks("1.1.2")
+
+	assert.EqualValues(t, FAIL, checks["1.1.1"].State)
+	assert.EqualValues(t, INFO, checks["1.1.2"].State)
+	assert.Equal(t, "Depends on check 1.1.1, which is FAIL instead of PASS", checks["1.1.2"].Reason)
+	assert.EqualValues(t, "", checks["1.1.3"].State)
+	assert.Equal(t, Summary{Fail: 1, Info: 1}, summary)
+	if assert.Len(t, controls.Groups, 2) {
+		assert.Equal(t, "1.1.2", controls.Groups[0].Checks[0].ID)
+		assert.Equal(t, "1.1.1", controls.Groups[1].Checks[0].ID)
+	}
+}
+
+func TestControls_CheckDependencies(t *testing.T) {
+	cases := []struct {
+		name      string
+		dependsOn map[string]string
+		err       string
+	}{
+		{
+			name:      "unknown check",
+			dependsOn: map[string]string{"1": "[9]"},
+			err:       `check 1 depends on unknown check "9"`,
+		},
+		{
+			name:      "unknown state",
+			dependsOn: map[string]string{"1": "[{check: 2, states: [OK]}]"},
+			err:       `check 1 depends on check 2 with unknown state "OK"`,
+		},
+		{
+			name:      "cycle",
+			dependsOn: map[string]string{"1": "[2]", "2": "[3]", "3": "[2]"},
+			err:       "checks depend on each other: 2 -> 3 -> 2",
+		},
+		{
+			name:      "self",
+			dependsOn: map[string]string{"1": "[1]"},
+			err:       "checks depend on each other: 1 -> 1",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			in := "---\ncontrols:\ngroups:\n- id: g\n  checks:\n"
+			for _, id := range []string{"1", "2", "3"} {
+				in += fmt.Sprintf("  - id: %q\n    audit: \"echo ok\"\n", id)
+				if dependsOn, ok := c.dependsOn[id]; ok {
+					in += "    depends_on: " + dependsOn + "\n"
+				}
+			}
+			_, err := NewBench().NewControls([]byte(in), nil)
+			if assert.Error(t, err) {
+				assert.Equal(t, c.err, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/bench-common/log"
+	"go.uber.org/zap"
+)
+
+// Detector detects facts about the target, e.g. platform=ubuntu, which fill
+// the defined constraints. A detector finding nothing returns no facts.
+type Detector func(target *Target) (map[string][]string, error)
+
+// Detect enables the detection of the defined constraints from the target
+// when passed to NewControls as a custom config. Values defined explicitly
+// override the detected ones.
+type Detect struct {
+	// Detectors are the names of the detectors to run, all by default.
+	Detectors []string
+	// Custom are the detectors of a project by name, which take precedence
+	// over the builtin detectors with the same name.
+	Custom map[string]Detector
+}
+
+const (
+	// DetectOSRelease detects platform, platform_like, platform_version and os
+	// from os-release.
+	DetectOSRelease = "os-release"
+	// DetectKernel detects the kernel release.
+	DetectKernel = "kernel"
+	// DetectInit detects the init system.
+	DetectInit = "init"
+	// DetectRuntime detects the container runtimes from their sockets.
+	DetectRuntime = "runtime"
+	// DetectKubernetes detects the versions of the Kubernetes components.
+	DetectKubernetes = "kubernetes"
+	// DetectCloud detects the cloud provider from DMI.
+	DetectCloud = "cloud"
+)
+
+// builtinDetectors holds the detectors which are available to every Bench.
+// Custom detectors of the Detect config take precedence over these.
+var builtinDetectors = map[string]Detector{
+	DetectOSRelease:  detectOSRelease,
+	DetectKernel:     detectKernel,
+	DetectInit:       detectInit,
+	DetectRuntime:    detectRuntime,
+	DetectKubernetes: detectKubernetes,
+	DetectCloud:      detectCloud,
+}
+
+// detectFrom returns the Detect custom config, if any.
+func detectFrom(customConfig []interface{}) (*Detect, bool) {
+	for _, config := range customConfig {
+		switch d := config.(type) {
+		case Detect:
+			return &d, true
+		case *Detect:
+			if d != nil {
+				return d, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// detect runs the detectors of d on the target, and merges their facts. A
+// failing detector is logged, and the others go on.
+func detect(target *Target, d *Detect) (map[string][]string, error) {
+	logger, err := log.ZapLogger(nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync() // nolint: errcheck
+
+	names := d.Detectors
+	if len(names) == 0 {
+		for name := range builtinDetectors {
+			names = append(names, name)
+		}
+		for name := range d.Custom {
+			if _, ok := builtinDetectors[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	facts := map[string][]string{}
+	for _, name := range names {
+		detector, ok := d.Custom[name]
+		if !ok {
+			if detector, ok = builtinDetectors[name]; !ok {
+				return nil, fmt.Errorf("detector %v is not registered", name)
+			}
+		}
+		detected, err := detector(target)
+		if err != nil {
+			logger.Debug("failed to detect constraints", zap.String("detector", name), zap.Error(err))
+			continue
+		}
+		for key, values := range detected {
+			for _, v := range values {
+				if !contains(facts[key], v) {
+					facts[key] = append(facts[key], v)
+				}
+			}
+		}
+	}
+	return facts, nil
+}
+
+// readTargetFile reads a file of the target, returning an empty string when
+// it doesn't exist.
+func readTargetFile(target *Target, name string) (string, error) {
+	b, err := target.fs().ReadFile(target.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+// detectOSRelease detects the distribution from os-release(5): platform is
+// its ID, platform_like the IDs of ID_LIKE, platform_version its VERSION_ID,
+// and os both, e.g. ubuntu-22.04.
+func detectOSRelease(target *Target) (map[string][]string, error) {
+	var content string
+	for _, name := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		var err error
+		if content, err = readTargetFile(target, name); err != nil {
+			return nil, err
+		}
+		if content != "" {
+			break
+		}
+	}
+
+	release := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		release[key] = value
+	}
+	if release["ID"] == "" {
+		return nil, nil
+	}
+
+	facts := map[string][]string{"platform": {release["ID"]}}
+	if like := strings.Fields(release["ID_LIKE"]); len(like) > 0 {
+		facts["platform_like"] = like
+	}
+	if version := release["VERSION_ID"]; version != "" {
+		facts["platform_version"] = []string{version}
+		facts["os"] = []string{release["ID"] + "-" + version}
+	}
+	return facts, nil
+}
+
+// detectKernel detects the release of the running kernel.
+func detectKernel(target *Target) (map[string][]string, error) {
+	if target.offline() {
+		return nil, nil
+	}
+	release, err := readTargetFile(target, "/proc/sys/kernel/osrelease")
+	if err != nil || release == "" {
+		return nil, err
+	}
+	return map[string][]string{"kernel": {release}}, nil
+}
+
+// detectInit detects the init system, systemd when it is booted with it, or
+// the command of pid 1.
+func detectInit(target *Target) (map[string][]string, error) {
+	if target.offline() {
+		return nil, nil
+	}
+	if info, err := target.fs().Lstat(target.path("/run/systemd/system")); err == nil && info.IsDir() {
+		return map[string][]string{"init": {"systemd"}}, nil
+	}
+	comm, err := readTargetFile(target, "/proc/1/comm")
+	if err != nil || comm == "" {
+		return nil, err
+	}
+	return map[string][]string{"init": {comm}}, nil
+}
+
+// runtimeSockets are the sockets of the container runtimes.
+var runtimeSockets = []struct {
+	runtime string
+	socket  string
+}{
+	{"docker", "/var/run/docker.sock"},
+	{"docker", "/run/docker.sock"},
+	{"containerd", "/run/containerd/containerd.sock"},
+	{"cri-o", "/var/run/crio/crio.sock"},
+	{"cri-o", "/run/crio/crio.sock"},
+	{"podman", "/run/podman/podman.sock"},
+}
+
+// detectRuntime detects the container runtimes whose socket exists.
+func detectRuntime(target *Target) (map[string][]string, error) {
+	if target.offline() {
+		return nil, nil
+	}
+	var runtimes []string
+	for _, s := range runtimeSockets {
+		if _, err := target.fs().Lstat(target.path(s.socket)); err == nil && !contains(runtimes, s.runtime) {
+			runtimes = append(runtimes, s.runtime)
+		}
+	}
+	if len(runtimes) == 0 {
+		return nil, nil
+	}
+	return map[string][]string{"runtime": runtimes}, nil
+}
+
+// kubernetesComponents are the binaries whose --version is detected.
+var kubernetesComponents = []string{"kubelet", "kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy", "k3s"}
+
+var kubernetesVersion = regexp.MustCompile(`\bv(\d+\.\d+(?:\.\d+)?\S*)`)
+
+// detectKubernetesTimeout bounds the --version of each component.
+const detectKubernetesTimeout = 10 * time.Second
+
+// detectKubernetes detects the version of the Kubernetes components on the
+// path, e.g. kubelet_version=1.26.1, and all their versions as
+// kubernetes_version.
+func detectKubernetes(target *Target) (map[string][]string, error) {
+	if !target.applicable(TypeExec, nil) {
+		return nil, nil
+	}
+	facts := map[string][]string{}
+	for _, component := range kubernetesComponents {
+		var stdout bytes.Buffer
+		ctx, cancel := context.WithTimeout(context.Background(), detectKubernetesTimeout)
+		err := target.run(ctx, targetCommand{args: []string{component, "--version"}, stdout: &stdout})
+		cancel()
+		if err != nil {
+			continue
+		}
+		m := kubernetesVersion.FindStringSubmatch(stdout.String())
+		if m == nil {
+			continue
+		}
+		facts[strings.ReplaceAll(component, "-", "_")+"_version"] = []string{m[1]}
+		if !contains(facts["kubernetes_version"], m[1]) {
+			facts["kubernetes_version"] = append(facts["kubernetes_version"], m[1])
+		}
+	}
+	return facts, nil
+}
+
+// cloudHints identify cloud providers from the DMI attributes of their
+// instances.
+var cloudHints = []struct {
+	cloud     string
+	attribute string
+	contains  string
+}{
+	{"aws", "sys_vendor", "amazon"},
+	{"aws", "bios_vendor", "amazon"},
+	{"aws", "product_version", "amazon"},
+	{"gcp", "sys_vendor", "google"},
+	{"gcp", "product_name", "google compute engine"},
+	{"azure", "chassis_asset_tag", "7783-7084-3265-9085-8269-3286-77"},
+	{"oci", "chassis_asset_tag", "oraclecloud.com"},
+	{"alibaba", "sys_vendor", "alibaba cloud"},
+	{"digitalocean", "sys_vendor", "digitalocean"},
+	{"openstack", "product_name", "openstack"},
+	{"hetzner", "sys_vendor", "hetzner"},
+}
+
+// detectCloud detects the cloud provider of the instance from its DMI
+// attributes.
+func detectCloud(target *Target) (map[string][]string, error) {
+	if target.offline() {
+		// /sys of an image would be that of the host it was built on
+		return nil, nil
+	}
+	attributes := map[string]string{}
+	for _, hint := range cloudHints {
+		value, ok := attributes[hint.attribute]
+		if !ok {
+			// Some attributes are only readable by root, which doesn't
+			// prevent the others from identifying the provider
+			v, _ := readTargetFile(target, "/sys/class/dmi/id/"+hint.attribute)
+			value = strings.ToLower(v)
+			attributes[hint.attribute] = value
+		}
+		if value != "" && strings.Contains(value, hint.contains) {
+			return map[string][]string{"cloud": {hint.cloud}}, nil
+		}
+	}
+	return nil, nil
+}
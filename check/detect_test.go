@@ -0,0 +1,158 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDetectTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/os-release":                 "NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID='9.2'\n# comment\n",
+		"proc/sys/kernel/osrelease":      "5.14.0-284.11.1.el9_2.x86_64\n",
+		"proc/1/comm":                    "tini\n",
+		"var/run/docker.sock":            "",
+		"run/containerd/containerd.sock": "",
+		"sys/class/dmi/id/sys_vendor":    "Amazon EC2\n",
+	})
+	return root
+}
+
+func TestDetectors(t *testing.T) {
+	root := writeDetectTree(t)
+	target := &Target{Root: root}
+
+	cases := []struct {
+		detector Detector
+		expected map[string][]string
+	}{
+		{
+			detector: detectOSRelease,
+			expected: map[string][]string{
+				"platform":         {"rocky"},
+				"platform_like":    {"rhel", "centos", "fedora"},
+				"platform_version": {"9.2"},
+				"os":               {"rocky-9.2"},
+			},
+		},
+		{detector: detectKernel, expected: map[string][]string{"kernel": {"5.14.0-284.11.1.el9_2.x86_64"}}},
+		{detector: detectInit, expected: map[string][]string{"init": {"tini"}}},
+		{detector: detectRuntime, expected: map[string][]string{"runtime": {"docker", "containerd"}}},
+		{detector: detectCloud, expected: map[string][]string{"cloud": {"aws"}}},
+	}
+	for _, c := range cases {
+		facts, err := c.detector(target)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, facts)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "run/systemd/system"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	facts, err := detectInit(target)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"init": {"systemd"}}, facts)
+}
+
+func TestDetectors_Empty(t *testing.T) {
+	empty := &Target{Root: t.TempDir()}
+	for _, detector := range []Detector{detectOSRelease, detectKernel, detectInit, detectRuntime, detectCloud} {
+		facts, err := detector(empty)
+		assert.NoError(t, err)
+		assert.Empty(t, facts)
+	}
+
+	// Offline targets have no running kernel, processes, sockets or instance
+	offline := &Target{Type: TargetRootFS, Root: writeDetectTree(t)}
+	for _, detector := range []Detector{detectKernel, detectInit, detectRuntime, detectKubernetes, detectCloud} {
+		facts, err := detector(offline)
+		assert.NoError(t, err)
+		assert.Empty(t, facts)
+	}
+}
+
+func TestDetectKubernetes(t *testing.T) {
+	bin := t.TempDir()
+	writeFiles(t, bin, map[string]string{
+		"kubelet":    "#!/bin/sh\necho 'Kubernetes v1.26.1'\n",
+		"kube-proxy": "#!/bin/sh\necho 'Kubernetes v1.26.1'\n",
+		"k3s":        "#!/bin/sh\necho 'k3s version v1.27.4+k3s1 (36645e73)'\n",
+	})
+	for _, name := range []string{"kubelet", "kube-proxy", "k3s"} {
+		if err := os.Chmod(filepath.Join(bin, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	facts, err := detectKubernetes(&Target{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"kubelet_version":    {"1.26.1"},
+		"kube_proxy_version": {"1.26.1"},
+		"k3s_version":        {"1.27.4+k3s1"},
+		"kubernetes_version": {"1.26.1", "1.27.4+k3s1"},
+	}, facts)
+}
+
+const detectControls = `---
+controls:
+id: 1
+text: "Detect"
+groups:
+- id: 1.1
+  text: "RHEL"
+  constraints:
+    platform: [rocky]
+    platform_like: [rhel]
+    kernel: [">=5.14"]
+  checks:
+    - id: 1.1.1
+      text: "Ensure the platform is detected"
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+`
+
+func TestBench_NewControlsDetect(t *testing.T) {
+	target := &Target{Root: writeDetectTree(t)}
+	b := NewBench()
+	custom := map[string]Detector{"site": func(*Target) (map[string][]string, error) {
+		return map[string][]string{"site": {"eu-west"}}, nil
+	}}
+
+	controls, err := b.NewControls([]byte(detectControls), nil, target, Detect{Custom: custom})
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Equal(t, []string{"rocky"}, controls.Detected["platform"])
+	assert.Equal(t, []string{"rhel", "centos", "fedora"}, controls.Detected["platform_like"])
+	assert.Equal(t, []string{"eu-west"}, controls.DefinedConstraints["site"])
+	assert.Equal(t, Summary{Pass: 1}, controls.RunGroup())
+
+	// Defined values override the detected ones
+	controls, err = b.NewControls([]byte(detectControls), []string{"platform=ubuntu"}, target, Detect{Detectors: []string{DetectOSRelease, DetectKernel}, Custom: custom})
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Equal(t, []string{"ubuntu"}, controls.DefinedConstraints["platform"])
+	assert.Equal(t, []string{"5.14.0-284.11.1.el9_2.x86_64"}, controls.DefinedConstraints["kernel"])
+	assert.Nil(t, controls.DefinedConstraints["site"])
+	assert.Equal(t, Summary{Info: 1}, controls.RunGroup())
+
+	// Without Detect nothing is detected
+	controls, err = b.NewControls([]byte(detectControls), nil, target)
+	if err != nil {
+		t.Fatalf("failed to load controls: %v", err)
+	}
+	assert.Nil(t, controls.Detected)
+
+	_, err = b.NewControls([]byte(detectControls), nil, target, Detect{Detectors: []string{"nosuchdetector"}})
+	assert.EqualError(t, err, "detector nosuchdetector is not registered")
+}
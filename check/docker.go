@@ -0,0 +1,155 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TypeDocker audits the Docker Engine API.
+const TypeDocker = "docker"
+
+const (
+	defaultDockerSocket = "/var/run/docker.sock"
+	dockerTimeout       = 30 * time.Second
+)
+
+// Docker queries, each inspected object of a list query is returned as a row.
+const (
+	dockerInfo       = "info"
+	dockerVersion    = "version"
+	dockerContainers = "containers"
+	dockerNetworks   = "networks"
+	dockerImages     = "images"
+)
+
+// DockerAudit queries the Docker Engine API over its unix socket, so checks
+// don't need the docker CLI.
+type DockerAudit struct {
+	Query      string `yaml:"query"`
+	Socket     string `yaml:"socket"`
+	APIVersion string `yaml:"api_version"`
+	// All includes stopped containers
+	All bool `yaml:"all"`
+}
+
+// Execute returns info and version as a single document, and the inspected
+// containers, networks or images as rows.
+func (d *DockerAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	client := newDockerClient(target, target.path(rootOrDefault(d.Socket, defaultDockerSocket)), d.APIVersion)
+
+	switch d.Query {
+	case dockerInfo, dockerVersion:
+		var doc interface{}
+		if err := client.get("/"+d.Query, &doc); err != nil {
+			return auditFailed(err)
+		}
+		return jsonResult(doc)
+	case dockerContainers:
+		query := ""
+		if d.All {
+			query = "?all=1"
+		}
+		return client.inspectAll("/containers/json"+query, "/containers/%s/json")
+	case dockerNetworks:
+		return client.inspectAll("/networks", "/networks/%s")
+	case dockerImages:
+		return client.inspectAll("/images/json", "/images/%s/json")
+	}
+	return auditFailed(fmt.Errorf("unknown docker query %q", d.Query))
+}
+
+type dockerClient struct {
+	http    *http.Client
+	baseURL string
+}
+
+func newDockerClient(target *Target, socket, apiVersion string) *dockerClient {
+	baseURL := "http://docker"
+	if apiVersion != "" {
+		baseURL += "/" + strings.TrimPrefix(apiVersion, "/")
+	}
+	return &dockerClient{
+		http:    unixSocketClient(target, socket, dockerTimeout),
+		baseURL: baseURL,
+	}
+}
+
+func (c *dockerClient) get(path string, v interface{}) error {
+	resp, err := c.http.Get(c.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("failed to query docker: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read docker response for %s: %v", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &dockerAPIError{statusCode: resp.StatusCode, status: resp.Status, path: path}
+		_ = json.Unmarshal(body, apiErr)
+		return apiErr
+	}
+	return json.Unmarshal(body, v)
+}
+
+// dockerAPIError is an error returned by the Docker Engine API.
+type dockerAPIError struct {
+	statusCode int
+	status     string
+	path       string
+	Message    string `json:"message"`
+}
+
+func (e *dockerAPIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("docker returned %s for %s: %s", e.status, e.path, e.Message)
+	}
+	return fmt.Sprintf("docker returned %s for %s", e.status, e.path)
+}
+
+// inspectAll lists the objects at listPath and inspects each of them. Objects
+// removed since they were listed are left out.
+func (c *dockerClient) inspectAll(listPath, inspectPath string) (result string, errMessage string, state State) {
+	var list []struct {
+		ID string `json:"Id"`
+	}
+	if err := c.get(listPath, &list); err != nil {
+		return auditFailed(err)
+	}
+
+	rows := make([]json.RawMessage, 0, len(list))
+	for _, item := range list {
+		var row json.RawMessage
+		if err := c.get(fmt.Sprintf(inspectPath, url.PathEscape(item.ID)), &row); err != nil {
+			var apiErr *dockerAPIError
+			if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound {
+				continue
+			}
+			return auditFailed(err)
+		}
+		rows = append(rows, row)
+	}
+	return jsonLinesResult(rows)
+}
@@ -0,0 +1,205 @@
+package check
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeDockerServer(t *testing.T) string {
+	// Image sha256:456 is listed, but removed before it is inspected
+	responses := map[string]interface{}{
+		"/v1.41/info":    map[string]interface{}{"SecurityOptions": []string{"name=seccomp,profile=default"}, "LoggingDriver": "json-file"},
+		"/v1.41/version": map[string]interface{}{"Version": "24.0.7", "ApiVersion": "1.43"},
+		"/v1.41/containers/json": []map[string]interface{}{
+			{"Id": "aaa", "Names": []string{"/web"}},
+			{"Id": "bbb", "Names": []string{"/privileged"}},
+		},
+		"/v1.41/containers/aaa/json":    map[string]interface{}{"Id": "aaa", "Name": "/web", "HostConfig": map[string]interface{}{"Privileged": false}},
+		"/v1.41/containers/bbb/json":    map[string]interface{}{"Id": "bbb", "Name": "/privileged", "HostConfig": map[string]interface{}{"Privileged": true}},
+		"/v1.41/networks":               []map[string]interface{}{{"Id": "net1", "Name": "bridge"}},
+		"/v1.41/networks/net1":          map[string]interface{}{"Id": "net1", "Name": "bridge", "Options": map[string]string{"com.docker.network.bridge.enable_icc": "false"}},
+		"/v1.41/images/json":            []map[string]interface{}{{"Id": "sha256:123"}, {"Id": "sha256:456"}},
+		"/v1.41/images/sha256:123/json": map[string]interface{}{"Id": "sha256:123", "Config": map[string]interface{}{"User": "app"}},
+	}
+
+	return newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if path == "/v1.41/containers/json" && r.URL.Query().Get("all") != "1" {
+			json.NewEncoder(w).Encode(responses[path].([]map[string]interface{})[:1])
+			return
+		}
+		resp, ok := responses[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "page not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestDockerAudit_Execute(t *testing.T) {
+	socket := newFakeDockerServer(t)
+
+	cases := []struct {
+		audit DockerAudit
+		ids   []string
+	}{
+		{audit: DockerAudit{Query: "containers"}, ids: []string{"aaa"}},
+		{audit: DockerAudit{Query: "containers", All: true}, ids: []string{"aaa", "bbb"}},
+		{audit: DockerAudit{Query: "networks"}, ids: []string{"net1"}},
+		{audit: DockerAudit{Query: "images"}, ids: []string{"sha256:123"}},
+	}
+
+	for _, c := range cases {
+		c.audit.Socket = socket
+		c.audit.APIVersion = "v1.41"
+		out, errMsg, state := c.audit.Execute()
+		if errMsg != "" || state != "" {
+			t.Fatalf("%s: unexpected failure: %s %s", c.audit.Query, state, errMsg)
+		}
+
+		var ids []string
+		for _, row := range parseRows[map[string]interface{}](t, out) {
+			ids = append(ids, row["Id"].(string))
+		}
+		assert.Equal(t, c.ids, ids, c.audit.Query)
+	}
+
+	out, errMsg, state := (&DockerAudit{Query: "version", Socket: socket, APIVersion: "v1.41"}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+	assert.JSONEq(t, `{"Version": "24.0.7", "ApiVersion": "1.43"}`, out)
+}
+
+func TestDockerAudit_Errors(t *testing.T) {
+	socket := newFakeDockerServer(t)
+
+	cases := []DockerAudit{
+		{Query: "volumes", Socket: socket},
+		{Query: "info", Socket: socket, APIVersion: "v1.12"},
+		{Query: "info", Socket: filepath.Join(t.TempDir(), "missing.sock")},
+	}
+	for _, c := range cases {
+		_, errMsg, state := c.Execute()
+		if state != WARN || errMsg == "" {
+			t.Errorf("%+v: expected WARN with an error message, got %q %q", c, state, errMsg)
+		}
+	}
+}
+
+func TestDockerAudit_Check(t *testing.T) {
+	socket := newFakeDockerServer(t)
+
+	controls := `---
+controls:
+id: 5
+text: "Container Runtime"
+groups:
+- id: 5.1
+  text: "Container Runtime"
+  checks:
+    - id: 5.1.1
+      text: "Ensure that privileged containers are not used"
+      audittype: "docker"
+      audit:
+        query: containers
+        all: true
+        socket: "` + socket + `"
+        api_version: v1.41
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.HostConfig.Privileged}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.1.2
+      text: "Ensure network traffic is restricted between containers on the default bridge"
+      audittype: "docker"
+      audit:
+        query: networks
+        socket: "` + socket + `"
+        api_version: v1.41
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.Options.com\\.docker\\.network\\.bridge\\.enable_icc}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, summary)
+}
+
+func TestDockerAudit_CheckNoContainers(t *testing.T) {
+	socket := newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.41/containers/json":
+			w.Write([]byte("[]\n")) // nolint: errcheck
+		case "/v1.41/info":
+			w.Write([]byte(`{"LoggingDriver": "json-file"}`)) // nolint: errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	controls := `---
+controls:
+id: 5
+text: "Container Runtime"
+groups:
+- id: 5.1
+  text: "Container Runtime"
+  checks:
+    - id: 5.1.1
+      text: "Ensure that privileged containers are not used"
+      audittype: "docker"
+      audit:
+        query: containers
+        socket: "` + socket + `"
+        api_version: v1.41
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.HostConfig.Privileged}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.1.2
+      text: "Ensure a logging driver is configured"
+      audittype: "docker"
+      audit:
+        query: info
+        socket: "` + socket + `"
+        api_version: v1.41
+      tests:
+        test_items:
+        - path: "{.LoggingDriver}"
+          compare:
+            op: eq
+            value: json-file
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	// No container is privileged on a host without containers
+	assert.Equal(t, Summary{Pass: 2}, c.RunGroup())
+}
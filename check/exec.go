@@ -0,0 +1,195 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TypeExec runs a command without a shell.
+const TypeExec = "exec"
+
+// exec outputs
+const (
+	execOutputStdout   = "stdout"
+	execOutputStderr   = "stderr"
+	execOutputCombined = "combined"
+	execOutputJSON     = "json"
+)
+
+var substitutionRe = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// Substitutions are the values substituted for ${name} in the arguments of
+// exec audits. Pass them to NewControls as a custom config.
+type Substitutions map[string]string
+
+// ExecAudit runs a command from an explicit argument list, so values are never
+// interpreted by a shell.
+type ExecAudit struct {
+	Args  []string          `yaml:"args"`
+	Stdin string            `yaml:"stdin"`
+	Env   map[string]string `yaml:"env"`
+	// ClearEnv runs the command with Env only, instead of adding Env to the
+	// environment of the bench.
+	ClearEnv bool   `yaml:"clear_env"`
+	Dir      string `yaml:"dir"`
+	// Output is "stdout" (the default), "stderr", "combined", or "json" for a
+	// document with the stdout, stderr and exit_code of the command.
+	Output  string `yaml:"output"`
+	Timeout string `yaml:"timeout"`
+}
+
+// ExecResult is the result of an ExecAudit with the json output.
+type ExecResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Execute runs the command. Like shell audits, a command exiting with a non
+// zero exit code is evaluated by the tests, with the exit code as error.
+func (e *ExecAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	res, errMessage, state := e.ExecuteResult(customConfig...)
+	return res.Output, errMessage, state
+}
+
+// ExecuteResult runs the command, reporting its stdout, stderr, exit code and
+// duration separately. Output is selected by the output setting.
+func (e *ExecAudit) ExecuteResult(customConfig ...interface{}) (result AuditResult, errMessage string, state State) {
+	if len(e.Args) == 0 {
+		return execFailed(fmt.Errorf("exec audit requires args"))
+	}
+	output := orDefault(e.Output, execOutputStdout)
+	switch output {
+	case execOutputStdout, execOutputStderr, execOutputCombined, execOutputJSON:
+	default:
+		return execFailed(fmt.Errorf("unknown exec output %q", e.Output))
+	}
+
+	subs := Substitutions{}
+	for _, config := range customConfig {
+		if s, ok := config.(Substitutions); ok {
+			for k, v := range s {
+				subs[k] = v
+			}
+		}
+	}
+
+	ctx := context.Background()
+	if e.Timeout != "" {
+		timeout, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			return execFailed(fmt.Errorf("invalid timeout %q: %v", e.Timeout, err))
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = subs.apply(arg)
+	}
+	var stdout, stderr bytes.Buffer
+	combined := &lockedBuffer{}
+	target := targetFrom(customConfig)
+
+	res := ExecResult{}
+	start := time.Now()
+	err := target.run(ctx, targetCommand{
+		args:     args,
+		stdin:    strings.NewReader(subs.apply(e.Stdin)),
+		stdout:   io.MultiWriter(&stdout, combined),
+		stderr:   io.MultiWriter(&stderr, combined),
+		env:      envList(e.Env, subs.apply),
+		clearEnv: e.ClearEnv,
+		dir:      subs.apply(e.Dir),
+	})
+	result.Duration = time.Since(start)
+	var exitErr *exitError
+	var notFoundErr *commandNotFoundError
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		// The command was killed, rather than completing in time
+		return execFailed(fmt.Errorf("%s timed out after %s", args[0], e.Timeout))
+	case errors.As(err, &notFoundErr):
+		// Reported like the shell does
+		result.Stderr, result.ExitCode = err.Error(), shellCommandNotFound
+		result.CommandNotFound = true
+		return result, err.Error(), ""
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.code
+		errMessage = err.Error()
+		// Remote hosts run commands with the login shell of the user, and
+		// chroot and nsenter run them for a shell wrapper. These exit with
+		// 127 when the command does not exist.
+		result.CommandNotFound = (target.ssh != nil || len(target.ShellWrapper) > 0) && res.ExitCode == shellCommandNotFound
+	default:
+		// The command could not be started, or did not complete
+		return execFailed(fmt.Errorf("failed to run %s: %v", args[0], err))
+	}
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	result.Stdout, result.Stderr, result.ExitCode = res.Stdout, res.Stderr, res.ExitCode
+
+	switch output {
+	case execOutputStderr:
+		result.Output = res.Stderr
+	case execOutputCombined:
+		result.Output = combined.buf.String()
+	case execOutputJSON:
+		result.Output, _, state = jsonResult(res)
+		return result, errMessage, state
+	default:
+		result.Output = res.Stdout
+	}
+	return result, errMessage, ""
+}
+
+// execFailed reports an exec audit which could not be carried out.
+func execFailed(err error) (result AuditResult, errMessage string, state State) {
+	_, errMessage, state = auditFailed(err)
+	return result, errMessage, state
+}
+
+// apply substitutes the ${name} placeholders of s. Unknown names are kept.
+func (s Substitutions) apply(v string) string {
+	return substitutionRe.ReplaceAllStringFunc(v, func(placeholder string) string {
+		if sub, ok := s[placeholder[2:len(placeholder)-1]]; ok {
+			return sub
+		}
+		return placeholder
+	})
+}
+
+// lockedBuffer is written by the stdout and stderr copying goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
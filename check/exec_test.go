@@ -0,0 +1,131 @@
+package check
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecAudit_Execute(t *testing.T) {
+	dir := t.TempDir()
+	script := []string{"sh", "-c", "echo out; sleep 0.1; echo err >&2; exit 3"}
+
+	cases := []struct {
+		name    string
+		audit   ExecAudit
+		subs    Substitutions
+		want    string
+		wantErr bool
+	}{
+		{name: "stdout", audit: ExecAudit{Args: script}, want: "out\n", wantErr: true},
+		{name: "stderr", audit: ExecAudit{Args: script, Output: "stderr"}, want: "err\n", wantErr: true},
+		{name: "combined", audit: ExecAudit{Args: script, Output: "combined"}, want: "out\nerr\n", wantErr: true},
+		{name: "json", audit: ExecAudit{Args: script, Output: "json"}, want: `{"stdout":"out\n","stderr":"err\n","exit_code":3}`, wantErr: true},
+		{name: "stdin", audit: ExecAudit{Args: []string{"cat"}, Stdin: "input"}, want: "input"},
+		{
+			name:  "env",
+			audit: ExecAudit{Args: []string{"sh", "-c", `echo "$GREETING"`}, Env: map[string]string{"GREETING": "hello"}},
+			want:  "hello\n",
+		},
+		{
+			name:  "clear env",
+			audit: ExecAudit{Args: []string{"/usr/bin/env"}, Env: map[string]string{"ONLY": "1"}, ClearEnv: true},
+			want:  "ONLY=1\n",
+		},
+		{name: "dir", audit: ExecAudit{Args: []string{"pwd", "-P"}, Dir: dir}, want: mustEvalSymlinks(t, dir) + "\n"},
+		{
+			name:  "substitutions",
+			audit: ExecAudit{Args: []string{"printf", "%s|%s\n", "${name}", "${unknown}"}},
+			subs:  Substitutions{"name": "it's a $(value)"},
+			want:  "it's a $(value)|${unknown}\n",
+		},
+		{
+			name:  "substituted stdin",
+			audit: ExecAudit{Args: []string{"cat"}, Stdin: "--config=${config}"},
+			subs:  Substitutions{"config": "/etc/app config.yaml"},
+			want:  "--config=/etc/app config.yaml",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute(c.subs)
+			assert.Equal(t, c.want, out)
+			assert.Equal(t, c.wantErr, errMsg != "", errMsg)
+			assert.Empty(t, state)
+		})
+	}
+}
+
+func TestExecAudit_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		audit ExecAudit
+	}{
+		{name: "no args", audit: ExecAudit{}},
+		{name: "unknown output", audit: ExecAudit{Args: []string{"true"}, Output: "stdin"}},
+		{name: "invalid timeout", audit: ExecAudit{Args: []string{"true"}, Timeout: "soon"}},
+		{name: "missing command", audit: ExecAudit{Args: []string{filepath.Join(t.TempDir(), "missing")}}},
+		{name: "timeout", audit: ExecAudit{Args: []string{"sleep", "5"}, Timeout: "100ms"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute()
+			assert.Empty(t, out)
+			assert.NotEmpty(t, errMsg)
+			assert.EqualValues(t, WARN, state)
+		})
+	}
+}
+
+func TestExecAudit_Check(t *testing.T) {
+	controls := `---
+controls:
+id: 1
+text: "Exec"
+groups:
+- id: 1.1
+  text: "Arguments"
+  checks:
+    - id: 1.1.1
+      text: "Ensure the argument is passed unchanged"
+      audittype: "exec"
+      audit:
+        args: ["printf", "%s", "${path}"]
+      tests:
+        test_items:
+        - compare:
+            op: eq
+            value: "/etc/my app; rm -rf /"
+      scored: true
+    - id: 1.1.2
+      text: "Ensure the command succeeds"
+      audittype: "exec"
+      audit:
+        args: ["sh", "-c", "exit 1"]
+        output: json
+      tests:
+        test_items:
+        - path: "{.exit_code}"
+          compare:
+            op: eq
+            value: 0
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil, Substitutions{"path": "/etc/my app; rm -rf /"})
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, summary)
+}
+
+func mustEvalSymlinks(t *testing.T, path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", path, err)
+	}
+	return strings.TrimSuffix(resolved, "/")
+}
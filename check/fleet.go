@@ -0,0 +1,244 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo/reporters"
+)
+
+// FleetHost is one of the hosts of a fleet run.
+type FleetHost struct {
+	// Name identifies the host in the report.
+	Name string
+	// Open returns the target of the host, e.g. connecting to it with
+	// DialSSH. The target is closed after the host's run. A nil Open, or a
+	// nil target, runs the controls on the host the bench runs on.
+	Open func() (*Target, error)
+}
+
+// Fleet runs the same controls against several hosts, and merges their
+// results in one report.
+type Fleet struct {
+	Hosts []FleetHost
+	// Parallel is the number of hosts audited at once, 1 by default.
+	Parallel int
+}
+
+// FleetReport holds the results of each host, and the results of each check
+// across hosts. Its Summary totals the checks of every host.
+type FleetReport struct {
+	ID          string             `json:"id"`
+	Description string             `json:"text"`
+	Hosts       []*FleetHostResult `json:"hosts"`
+	Checks      []*FleetCheck      `json:"checks"`
+	Summary
+	FleetSummary
+}
+
+// FleetSummary counts the hosts of a fleet run.
+type FleetSummary struct {
+	HostsTotal       int `json:"hosts_total"`
+	HostsUnreachable int `json:"hosts_unreachable"`
+	// HostsFailed counts the hosts with at least one failing check.
+	HostsFailed int `json:"hosts_failed"`
+}
+
+// FleetHostResult is the result of the controls on one host.
+type FleetHostResult struct {
+	Name   string          `json:"name"`
+	Target *TargetMetadata `json:"target,omitempty"`
+	// Error is the reason the host could not be audited, e.g. it is
+	// unreachable. The run goes on with the other hosts.
+	Error string `json:"error,omitempty"`
+	Summary
+	Controls *Controls `json:"controls,omitempty"`
+}
+
+// FleetCheck is the result of a check across the hosts it ran on.
+type FleetCheck struct {
+	ID          string `json:"test_number"`
+	Description string `json:"test_desc"`
+	// Hosts lists the names of the hosts in each state.
+	Hosts map[State][]string `json:"hosts"`
+	Summary
+}
+
+// Run loads the controls for each host with bench, and runs them with run,
+// e.g. func(c *Controls) Summary { return c.RunGroup() }. Custom configs are
+// passed to NewControls along with the target of the host.
+func (f *Fleet) Run(b Bench, in []byte, definitions []string, run func(*Controls) Summary, customConfigs ...interface{}) *FleetReport {
+	report := &FleetReport{Hosts: make([]*FleetHostResult, len(f.Hosts)), Checks: []*FleetCheck{}}
+
+	parallel := f.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+	running := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, host := range f.Hosts {
+		if host.Name == "" {
+			host.Name = fmt.Sprintf("host-%d", i+1)
+		}
+		wg.Add(1)
+		running <- struct{}{}
+		go func(i int, host FleetHost) {
+			defer wg.Done()
+			defer func() { <-running }()
+			report.Hosts[i] = runFleetHost(b, in, definitions, host, run, customConfigs)
+		}(i, host)
+	}
+	wg.Wait()
+
+	report.aggregate()
+	return report
+}
+
+func runFleetHost(b Bench, in []byte, definitions []string, host FleetHost, run func(*Controls) Summary, customConfigs []interface{}) *FleetHostResult {
+	result := &FleetHostResult{Name: host.Name}
+	target := defaultTarget
+	if host.Open != nil {
+		t, err := host.Open()
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if t != nil {
+			defer t.Close() // nolint: errcheck
+			target = t
+		}
+	}
+	result.Target = target.metadata()
+
+	// The target of the host comes first, so it is the one audits use
+	configs := append([]interface{}{target}, customConfigs...)
+	controls, err := b.NewControls(in, definitions, configs...)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Summary = run(controls)
+	result.Controls = controls
+	return result
+}
+
+// aggregate totals the results of the hosts, and builds the results of each
+// check across hosts, in the order the checks were first run.
+func (r *FleetReport) aggregate() {
+	checks := map[string]*FleetCheck{}
+	r.HostsTotal = len(r.Hosts)
+	for _, host := range r.Hosts {
+		if host.Controls == nil {
+			r.HostsUnreachable++
+			continue
+		}
+		if r.ID == "" && r.Description == "" {
+			r.ID, r.Description = host.Controls.ID, orDefault(host.Controls.Description, host.Controls.Text)
+		}
+		r.Pass += host.Pass
+		r.Fail += host.Fail
+		r.Warn += host.Warn
+		r.Info += host.Info
+		r.NotApplicable += host.NotApplicable
+		if host.Fail > 0 {
+			r.HostsFailed++
+		}
+
+		WalkGroups(host.Controls.Groups, func(group *Group, _ []*Group) {
+			for _, check := range group.Checks {
+				if check.State == "" {
+					continue
+				}
+				fc, ok := checks[check.ID]
+				if !ok {
+					fc = &FleetCheck{
+						ID:          check.ID,
+						Description: orDefault(check.Description, check.Text),
+						Hosts:       map[State][]string{},
+					}
+					checks[check.ID] = fc
+					r.Checks = append(r.Checks, fc)
+				}
+				fc.Hosts[check.State] = append(fc.Hosts[check.State], host.Name)
+				fc.Summary.add(check.State)
+			}
+		})
+	}
+}
+
+// add counts a check in state.
+func (s *Summary) add(state State) {
+	switch state {
+	case PASS:
+		s.Pass++
+	case FAIL:
+		s.Fail++
+	case WARN:
+		s.Warn++
+	case INFO:
+		s.Info++
+	case NOTAPPLICABLE:
+		s.NotApplicable++
+	}
+}
+
+// JSON encodes the fleet report to JSON.
+func (r *FleetReport) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// junitTestSuites holds a test suite for each host.
+type junitTestSuites struct {
+	XMLName xml.Name                   `xml:"testsuites"`
+	Suites  []reporters.JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnit encodes the fleet report to JUnit, with a test suite for each host.
+// An unreachable host is a suite with a single failing test case.
+func (r *FleetReport) JUnit() ([]byte, error) {
+	suites := junitTestSuites{}
+	for _, host := range r.Hosts {
+		if host.Controls == nil {
+			suites.Suites = append(suites.Suites, reporters.JUnitTestSuite{
+				Name: host.Name,
+				TestCases: []reporters.JUnitTestCase{{
+					Name:           "host could not be audited",
+					ClassName:      host.Name,
+					FailureMessage: &reporters.JUnitFailureMessage{Message: host.Error},
+				}},
+				Tests:    1,
+				Failures: 1,
+			})
+			continue
+		}
+		suite := host.Controls.junitSuite()
+		suite.Name = strings.TrimSpace(host.Name + " " + r.Description)
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	var b bytes.Buffer
+	encoder := xml.NewEncoder(&b)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(suites); err != nil {
+		return nil, fmt.Errorf("Failed to generate JUnit report: %s", err.Error())
+	}
+	return b.Bytes(), nil
+}
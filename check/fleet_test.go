@@ -0,0 +1,134 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fleetControls = `---
+controls:
+id: 6
+text: "System Maintenance"
+groups:
+- id: 6.2
+  text: "User and Group Settings"
+  checks:
+    - id: 6.2.1
+      text: "Ensure root is the only UID 0 account"
+      audittype: "accounts"
+      tests:
+        test_items:
+        - path: "{.uid0_users}"
+          compare:
+            op: eq
+            value: "[\"root\"]"
+      scored: true
+    - id: 6.2.2
+      text: "Ensure the shell runs"
+      audit: "echo ok"
+      tests:
+        test_items:
+        - flag: "ok"
+      scored: true
+`
+
+func TestFleet_Run(t *testing.T) {
+	good, bad := t.TempDir(), t.TempDir()
+	writeFiles(t, good, map[string]string{
+		"etc/passwd": "root:x:0:0:root:/root:/bin/bash\n",
+		"etc/group":  "root:x:0:\n",
+	})
+	writeFiles(t, bad, map[string]string{
+		"etc/passwd": "root:x:0:0:root:/root:/bin/bash\ntoor:x:0:0::/root:/bin/sh\n",
+		"etc/group":  "root:x:0:\n",
+	})
+	server := newTestSSHServer(t)
+
+	fleet := &Fleet{
+		Parallel: 2,
+		Hosts: []FleetHost{
+			{Name: "good", Open: func() (*Target, error) { return &Target{Root: good}, nil }},
+			{Name: "bad", Open: func() (*Target, error) {
+				target, err := DialSSH(SSHConfig{
+					Address:    server.addr,
+					User:       "bench",
+					KeyFiles:   []string{server.keyFile},
+					KnownHosts: []string{server.knownHosts},
+				})
+				if err != nil {
+					return nil, err
+				}
+				target.Root = bad
+				return target, nil
+			}},
+			{Name: "down", Open: func() (*Target, error) { return nil, fmt.Errorf("connection refused") }},
+		},
+	}
+	report := fleet.Run(NewBench(), []byte(fleetControls), nil, func(c *Controls) Summary { return c.RunGroup() })
+
+	assert.Equal(t, "6", report.ID)
+	assert.Equal(t, "System Maintenance", report.Description)
+	if assert.Len(t, report.Hosts, 3) {
+		assert.Equal(t, Summary{Pass: 2}, report.Hosts[0].Summary)
+		assert.Equal(t, &TargetMetadata{Type: TargetHost, Root: good}, report.Hosts[0].Target)
+		assert.Equal(t, Summary{Pass: 1, Fail: 1}, report.Hosts[1].Summary)
+		assert.Equal(t, TargetSSH, report.Hosts[1].Target.Type)
+		assert.Equal(t, "connection refused", report.Hosts[2].Error)
+		assert.Nil(t, report.Hosts[2].Controls)
+	}
+	assert.Equal(t, Summary{Pass: 3, Fail: 1}, report.Summary)
+	assert.Equal(t, FleetSummary{HostsTotal: 3, HostsUnreachable: 1, HostsFailed: 1}, report.FleetSummary)
+
+	if assert.Len(t, report.Checks, 2) {
+		assert.Equal(t, "6.2.1", report.Checks[0].ID)
+		assert.Equal(t, map[State][]string{PASS: {"good"}, FAIL: {"bad"}}, report.Checks[0].Hosts)
+		assert.Equal(t, Summary{Pass: 1, Fail: 1}, report.Checks[0].Summary)
+		assert.Equal(t, map[State][]string{PASS: {"good", "bad"}}, report.Checks[1].Hosts)
+	}
+
+	out, err := report.JSON()
+	if err != nil {
+		t.Fatalf("failed to encode report: %v", err)
+	}
+	var decoded struct {
+		Unreachable int `json:"hosts_unreachable"`
+		Fail        int `json:"total_fail"`
+		Hosts       []struct {
+			Name string `json:"name"`
+			Fail int    `json:"total_fail"`
+		} `json:"hosts"`
+		Checks []struct {
+			Hosts map[string][]string `json:"hosts"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	assert.Equal(t, 1, decoded.Unreachable)
+	assert.Equal(t, 1, decoded.Fail)
+	assert.Equal(t, 1, decoded.Hosts[1].Fail)
+	assert.Equal(t, []string{"bad"}, decoded.Checks[0].Hosts["FAIL"])
+
+	junit, err := report.JUnit()
+	if err != nil {
+		t.Fatalf("failed to encode report: %v", err)
+	}
+	assert.True(t, strings.HasPrefix(string(junit), "<testsuites>"))
+	assert.Equal(t, 3, strings.Count(string(junit), "<testsuite "))
+	assert.Contains(t, string(junit), `name="good System Maintenance"`)
+	assert.Contains(t, string(junit), "connection refused")
+}
+
+func TestFleet_RunDefaults(t *testing.T) {
+	fleet := &Fleet{Hosts: []FleetHost{{}}}
+	report := fleet.Run(NewBench(), []byte(fleetControls), nil, func(c *Controls) Summary { return c.RunChecks("6.2.2") })
+
+	assert.Equal(t, "host-1", report.Hosts[0].Name)
+	assert.Equal(t, &TargetMetadata{Type: TargetHost}, report.Hosts[0].Target)
+	assert.Equal(t, Summary{Pass: 1}, report.Summary)
+	assert.Len(t, report.Checks, 1)
+}
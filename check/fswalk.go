@@ -0,0 +1,402 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// TypeFSWalk audits the files of a filesystem tree, like `find / -xdev -perm -4000`.
+const TypeFSWalk = "fs_walk"
+
+// fs_walk predicates
+const (
+	predicateWorldWritable = "world_writable"
+	predicateSetuid        = "setuid"
+	predicateSetgid        = "setgid"
+	predicateNoOwner       = "no_owner"
+	predicateNoGroup       = "no_group"
+	predicateStickyMissing = "sticky_missing"
+)
+
+var fsWalkPredicates = []string{
+	predicateWorldWritable,
+	predicateSetuid,
+	predicateSetgid,
+	predicateNoOwner,
+	predicateNoGroup,
+	predicateStickyMissing,
+}
+
+const defaultFSWalkMaxRows = 1000
+
+// defaultSkipFSTypes are the pseudo and network filesystems which are not
+// walked into when crossing mount points.
+var defaultSkipFSTypes = []string{
+	"autofs", "binfmt_misc", "bpf", "cgroup", "cgroup2", "cifs", "configfs", "debugfs",
+	"devpts", "devtmpfs", "fuse.sshfs", "fusectl", "hugetlbfs", "mqueue", "nfs", "nfs4",
+	"nsfs", "overlay", "proc", "pstore", "securityfs", "smb3", "sysfs", "tracefs",
+}
+
+// FSWalkAudit walks the trees under Roots and reports the files matching any
+// of Predicates, all of them by default. Like `find -xdev` it stays on the
+// filesystem of each root, unless CrossMounts is set.
+type FSWalkAudit struct {
+	Roots      []string `yaml:"roots"`
+	Predicates []string `yaml:"predicates"`
+	// Exclude are glob patterns of paths which are not reported or walked
+	// into, e.g. "/proc" or "/var/lib/docker/*". "**" matches any number of
+	// path elements.
+	Exclude     []string `yaml:"exclude"`
+	CrossMounts bool     `yaml:"cross_mounts"`
+	// SkipFSTypes are the filesystem types which are not walked into when
+	// crossing mount points, instead of the pseudo and network filesystems.
+	SkipFSTypes []string `yaml:"skip_fs_types"`
+	// Summary returns only the counts instead of the matching files
+	Summary bool `yaml:"summary"`
+	// MaxRows limits the matching files returned. When more files match,
+	// the audit fails rather than test some of them only.
+	MaxRows  int    `yaml:"max_rows"`
+	Root     string `yaml:"root"`
+	ProcRoot string `yaml:"proc_root"`
+}
+
+// FSWalkMatch is a file matching fs_walk predicates.
+type FSWalkMatch struct {
+	Path       string   `json:"path"`
+	Type       string   `json:"type"`
+	Mode       string   `json:"mode"`
+	UID        uint32   `json:"uid"`
+	GID        uint32   `json:"gid"`
+	Predicates []string `json:"predicates"`
+}
+
+// FSWalkSummary counts the files matching each of the fs_walk predicates.
+type FSWalkSummary struct {
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"`
+	Walked int            `json:"walked"`
+	Errors []string       `json:"errors"`
+}
+
+// Execute returns a row for each matching file, or the summary alone. Rows
+// are written as they are found, so only the directory being read is held
+// in memory.
+func (w *FSWalkAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	walker, err := w.newWalker(targetFrom(customConfig))
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	roots := w.Roots
+	if len(roots) == 0 {
+		roots = []string{"/"}
+	}
+	for _, root := range roots {
+		if err := walker.walk(path.Clean("/" + root)); err != nil {
+			return auditFailed(err)
+		}
+	}
+
+	if w.Summary {
+		return jsonResult(walker.summary)
+	}
+	if walker.summary.Total > walker.maxRows {
+		return auditFailed(fmt.Errorf("%d files match, more than max_rows %d: raise max_rows, or test the summary", walker.summary.Total, walker.maxRows))
+	}
+	return walker.out.String(), "", ""
+}
+
+type fsWalker struct {
+	audit      *FSWalkAudit
+	fs         targetFS
+	root       string
+	predicates map[string]bool
+	// skipMounts are the mount points of filesystems which are not walked into
+	skipMounts map[string]bool
+	exclude    []*regexp.Regexp
+	users      map[uint32]bool
+	groups     map[uint32]bool
+	maxRows    int
+	summary    FSWalkSummary
+	out        strings.Builder
+}
+
+func (w *FSWalkAudit) newWalker(target *Target) (*fsWalker, error) {
+	walker := &fsWalker{
+		audit:      w,
+		fs:         target.fs(),
+		root:       target.path(rootOrDefault(w.Root, "/")),
+		predicates: map[string]bool{},
+		skipMounts: map[string]bool{},
+		maxRows:    w.MaxRows,
+		summary:    FSWalkSummary{Counts: map[string]int{}, Errors: []string{}},
+	}
+	if walker.maxRows <= 0 {
+		walker.maxRows = defaultFSWalkMaxRows
+	}
+
+	predicates := w.Predicates
+	if len(predicates) == 0 {
+		predicates = fsWalkPredicates
+	}
+	for _, p := range predicates {
+		if !contains(fsWalkPredicates, p) {
+			return nil, fmt.Errorf("unknown fs_walk predicate %q", p)
+		}
+		walker.predicates[p] = true
+		walker.summary.Counts[p] = 0
+	}
+
+	for _, pattern := range w.Exclude {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+		}
+		walker.exclude = append(walker.exclude, re)
+	}
+
+	if walker.predicates[predicateNoOwner] || walker.predicates[predicateNoGroup] {
+		accounts, err := readAccounts(walker.fs, filepath.Join(walker.root, "etc"))
+		if err != nil {
+			return nil, err
+		}
+		walker.users, walker.groups = map[uint32]bool{}, map[uint32]bool{}
+		for _, u := range accounts.Users {
+			walker.users[uint32(u.UID)] = true
+		}
+		for _, g := range accounts.Groups {
+			walker.groups[uint32(g.GID)] = true
+		}
+	}
+
+	if w.CrossMounts {
+		skip := w.SkipFSTypes
+		if len(skip) == 0 {
+			skip = defaultSkipFSTypes
+		}
+		mountinfo := mountInfoPath(target, w.ProcRoot)
+		data, err := walker.fs.ReadFile(mountinfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", mountinfo, err)
+		}
+		mounts, _ := parseMountInfo(data)
+		for mp, entry := range mounts {
+			if contains(skip, entry.FSType) {
+				walker.skipMounts[mp] = true
+			}
+		}
+	}
+	return walker, nil
+}
+
+// walk walks the tree at name, a path relative to the root of the walker.
+func (fw *fsWalker) walk(name string) error {
+	start := filepath.Join(fw.root, name)
+	info, err := fw.fs.Lstat(start)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to walk %s: %v", name, err)
+	}
+	rootDev, ok := fileDevice(info)
+	// SFTP does not report devices, filesystems are told apart by their
+	// mount points only
+	_, remote := info.Sys().(*remoteFileStat)
+	if !ok && !remote {
+		return fmt.Errorf("fs_walk is not supported on this platform")
+	}
+
+	return fw.fs.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are reported, and the walk goes on
+			fw.summary.Errors = append(fw.summary.Errors, err.Error())
+			return nil
+		}
+		rel, _ := filepath.Rel(fw.root, p)
+		rel = path.Clean("/" + filepath.ToSlash(rel))
+		if fw.excluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && p != start {
+			if fw.skipMounts[rel] {
+				return filepath.SkipDir
+			}
+			if dev, _ := fileDevice(info); !remote && dev != rootDev && !fw.audit.CrossMounts {
+				return filepath.SkipDir
+			}
+		}
+
+		fw.summary.Walked++
+		fw.match(rel, info)
+		return nil
+	})
+}
+
+func (fw *fsWalker) match(name string, info fs.FileInfo) {
+	mode := info.Mode()
+	if mode&fs.ModeSymlink != 0 {
+		return
+	}
+
+	var matched []string
+	worldWritable := mode.Perm()&0o002 != 0
+	if fw.predicates[predicateWorldWritable] && worldWritable && mode.IsRegular() {
+		matched = append(matched, predicateWorldWritable)
+	}
+	if fw.predicates[predicateSetuid] && mode&fs.ModeSetuid != 0 {
+		matched = append(matched, predicateSetuid)
+	}
+	if fw.predicates[predicateSetgid] && mode&fs.ModeSetgid != 0 && !mode.IsDir() {
+		matched = append(matched, predicateSetgid)
+	}
+	uid, gid, _ := fileOwner(info)
+	if st, ok := info.Sys().(*remoteFileStat); ok {
+		uid, gid = st.UID, st.GID
+	}
+	if fw.predicates[predicateNoOwner] && !fw.users[uid] {
+		matched = append(matched, predicateNoOwner)
+	}
+	if fw.predicates[predicateNoGroup] && !fw.groups[gid] {
+		matched = append(matched, predicateNoGroup)
+	}
+	if fw.predicates[predicateStickyMissing] && worldWritable && mode.IsDir() && mode&fs.ModeSticky == 0 {
+		matched = append(matched, predicateStickyMissing)
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	fw.summary.Total++
+	for _, p := range matched {
+		fw.summary.Counts[p]++
+	}
+	if fw.audit.Summary {
+		return
+	}
+	if fw.summary.Total > fw.maxRows {
+		return
+	}
+
+	row, _ := json.Marshal(FSWalkMatch{
+		Path:       name,
+		Type:       fileType(mode),
+		Mode:       fmt.Sprintf("%04o", unixPerm(mode)),
+		UID:        uid,
+		GID:        gid,
+		Predicates: matched,
+	})
+	fw.out.Write(row)
+	fw.out.WriteByte('\n')
+}
+
+// excluded tests if name matches one of the exclude patterns.
+func (fw *fsWalker) excluded(name string) bool {
+	for _, re := range fw.exclude {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp translates a path.Match pattern to a regular expression, where
+// "**" also matches any number of path elements.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "^") {
+				class = "\\" + class
+			} else if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return "other"
+}
+
+// unixPerm returns the permission bits of mode as chmod(1) writes them.
+func unixPerm(mode fs.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 0o1000
+	}
+	return perm
+}
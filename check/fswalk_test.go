@@ -0,0 +1,282 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFSWalkTree creates a tree with one file or directory for each of the
+// fs_walk predicates, owned by the current user.
+func writeFSWalkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/passwd":          fmt.Sprintf("root:x:0:0:root:/root:/bin/bash\ntest:x:%d:%d::/home/test:/bin/sh\n", os.Getuid(), os.Getgid()),
+		"etc/group":           fmt.Sprintf("root:x:0:\ntest:x:%d:\n", os.Getgid()),
+		"usr/bin/passwd":      "",
+		"usr/bin/wall":        "",
+		"usr/bin/ls":          "",
+		"srv/data/shared.txt": "",
+		"var/cache/x.txt":     "",
+	})
+	for name, mode := range map[string]os.FileMode{
+		"usr/bin/passwd":      0755 | os.ModeSetuid,
+		"usr/bin/wall":        0755 | os.ModeSetgid,
+		"srv/data/shared.txt": 0666,
+		"var/cache/x.txt":     0666,
+	} {
+		if err := os.Chmod(filepath.Join(root, name), mode); err != nil {
+			t.Fatalf("failed to chmod %s: %v", name, err)
+		}
+	}
+	for name, mode := range map[string]os.FileMode{
+		"tmp":     0777 | os.ModeSticky,
+		"srv/tmp": 0777,
+	} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := os.Chmod(filepath.Join(root, name), mode); err != nil {
+			t.Fatalf("failed to chmod %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestFSWalkAudit_Execute(t *testing.T) {
+	root := writeFSWalkTree(t)
+
+	audit := FSWalkAudit{Root: root, Exclude: []string{"/var/cache"}}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	matches := map[string]FSWalkMatch{}
+	for _, m := range parseRows[FSWalkMatch](t, out) {
+		matches[m.Path] = m
+	}
+	assert.Len(t, matches, 4)
+	assert.Equal(t, []string{predicateSetuid}, matches["/usr/bin/passwd"].Predicates)
+	assert.Equal(t, "4755", matches["/usr/bin/passwd"].Mode)
+	assert.Equal(t, []string{predicateSetgid}, matches["/usr/bin/wall"].Predicates)
+	assert.Equal(t, []string{predicateWorldWritable}, matches["/srv/data/shared.txt"].Predicates)
+	assert.Equal(t, "file", matches["/srv/data/shared.txt"].Type)
+	assert.Equal(t, []string{predicateStickyMissing}, matches["/srv/tmp"].Predicates)
+	assert.Equal(t, "directory", matches["/srv/tmp"].Type)
+	assert.Equal(t, uint32(os.Getuid()), matches["/srv/tmp"].UID)
+}
+
+func TestFSWalkAudit_Summary(t *testing.T) {
+	root := writeFSWalkTree(t)
+
+	cases := []struct {
+		name  string
+		audit FSWalkAudit
+		want  FSWalkSummary
+	}{
+		{
+			name:  "all predicates",
+			audit: FSWalkAudit{Root: root},
+			want: FSWalkSummary{Total: 5, Counts: map[string]int{
+				predicateWorldWritable: 2, predicateSetuid: 1, predicateSetgid: 1,
+				predicateNoOwner: 0, predicateNoGroup: 0, predicateStickyMissing: 1,
+			}},
+		},
+		{
+			name:  "roots and predicates",
+			audit: FSWalkAudit{Root: root, Roots: []string{"/srv", "/usr"}, Predicates: []string{predicateWorldWritable, predicateSetuid}},
+			want:  FSWalkSummary{Total: 2, Counts: map[string]int{predicateWorldWritable: 1, predicateSetuid: 1}},
+		},
+		{
+			name:  "recursive glob",
+			audit: FSWalkAudit{Root: root, Exclude: []string{"/**/*.txt"}, Predicates: []string{predicateWorldWritable}},
+			want:  FSWalkSummary{Total: 0, Counts: map[string]int{predicateWorldWritable: 0}},
+		},
+		{
+			name:  "missing root",
+			audit: FSWalkAudit{Root: root, Roots: []string{"/opt"}, Predicates: []string{predicateSetuid}},
+			want:  FSWalkSummary{Total: 0, Counts: map[string]int{predicateSetuid: 0}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.audit.Summary = true
+			out, errMsg, state := c.audit.Execute()
+			assert.Empty(t, errMsg)
+			assert.Empty(t, state)
+
+			summary := parseRows[FSWalkSummary](t, out)[0]
+			assert.Equal(t, c.want.Total, summary.Total)
+			assert.Equal(t, c.want.Counts, summary.Counts)
+		})
+	}
+}
+
+func TestFSWalkAudit_Unowned(t *testing.T) {
+	root := writeFSWalkTree(t)
+	writeFiles(t, root, map[string]string{
+		"etc/passwd": "nobody:x:65534:65534::/:/usr/sbin/nologin\n",
+		"etc/group":  "nogroup:x:65534:\n",
+	})
+	if os.Getuid() == 65534 || os.Getgid() == 65534 {
+		t.Skip("the test files are owned by nobody")
+	}
+
+	audit := FSWalkAudit{Root: root, Roots: []string{"/usr"}, Predicates: []string{predicateNoOwner, predicateNoGroup}, Summary: true}
+	out, errMsg, _ := audit.Execute()
+	assert.Empty(t, errMsg)
+	summary := parseRows[FSWalkSummary](t, out)[0]
+	// /usr, /usr/bin and its three files
+	assert.Equal(t, map[string]int{predicateNoOwner: 5, predicateNoGroup: 5}, summary.Counts)
+}
+
+func TestFSWalkAudit_MaxRows(t *testing.T) {
+	root := writeFSWalkTree(t)
+
+	// Testing some of the files only would be misleading
+	audit := FSWalkAudit{Root: root, MaxRows: 1, Predicates: []string{predicateWorldWritable}}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, out)
+	assert.Equal(t, "2 files match, more than max_rows 1: raise max_rows, or test the summary", errMsg)
+	assert.EqualValues(t, WARN, state)
+
+	audit.MaxRows = 2
+	out, errMsg, state = audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Len(t, parseRows[FSWalkMatch](t, out), 2)
+
+	audit.MaxRows, audit.Summary = 1, true
+	out, _, _ = audit.Execute()
+	summary := parseRows[FSWalkSummary](t, out)[0]
+	// The counts are not limited
+	assert.Equal(t, 2, summary.Total)
+	assert.Equal(t, map[string]int{predicateWorldWritable: 2}, summary.Counts)
+}
+
+func TestFSWalkAudit_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		audit FSWalkAudit
+	}{
+		{name: "unknown predicate", audit: FSWalkAudit{Root: t.TempDir(), Predicates: []string{"executable"}}},
+		{name: "invalid exclude", audit: FSWalkAudit{Root: t.TempDir(), Predicates: []string{predicateSetuid}, Exclude: []string{"/[a"}}},
+		{name: "no passwd", audit: FSWalkAudit{Root: t.TempDir(), Predicates: []string{predicateNoOwner}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, errMsg, state := c.audit.Execute()
+			assert.NotEmpty(t, errMsg)
+			assert.EqualValues(t, WARN, state)
+		})
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "/proc", name: "/proc", want: true},
+		{pattern: "/proc", name: "/proc/1", want: false},
+		{pattern: "/var/lib/docker/*", name: "/var/lib/docker/overlay2", want: true},
+		{pattern: "/var/lib/docker/*", name: "/var/lib/docker/overlay2/x", want: false},
+		{pattern: "/home/**", name: "/home/a/b/c", want: true},
+		{pattern: "/**/*.log", name: "/var/log/syslog.log", want: true},
+		{pattern: "/**/*.log", name: "/var/log/syslog", want: false},
+		{pattern: "/tmp/?", name: "/tmp/a", want: true},
+		{pattern: "/tmp/[!a]", name: "/tmp/a", want: false},
+		{pattern: "/tmp/[!a]", name: "/tmp/b", want: true},
+		{pattern: "/a.b", name: "/axb", want: false},
+	}
+	for _, c := range cases {
+		re, err := globRegexp(c.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", c.pattern, err)
+		}
+		assert.Equal(t, c.want, re.MatchString(c.name), "%s matching %s", c.pattern, c.name)
+	}
+}
+
+func TestFSWalkAudit_Check(t *testing.T) {
+	root := writeFSWalkTree(t)
+
+	controls := `---
+controls:
+id: 6
+text: "System Maintenance"
+groups:
+- id: 6.1
+  text: "System File Permissions"
+  checks:
+    - id: 6.1.1
+      text: "Ensure no world writable files exist"
+      audittype: "fs_walk"
+      audit:
+        root: "` + root + `"
+        predicates: ["world_writable"]
+        exclude: ["/var/cache"]
+        summary: true
+      tests:
+        test_items:
+        - path: "{.total}"
+          compare:
+            op: eq
+            value: 0
+      scored: true
+    - id: 6.1.2
+      text: "Ensure no unowned files or directories exist"
+      audittype: "fs_walk"
+      audit:
+        root: "` + root + `"
+        predicates: ["no_owner", "no_group"]
+        summary: true
+      tests:
+        test_items:
+        - path: "{.total}"
+          compare:
+            op: eq
+            value: 0
+      scored: true
+    - id: 6.1.3
+      text: "Audit SUID executables"
+      audittype: "fs_walk"
+      audit:
+        root: "` + root + `"
+        predicates: ["setuid"]
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.path}"
+          compare:
+            op: eq
+            value: "/usr/bin/passwd"
+      scored: true
+    - id: 6.1.4
+      text: "Audit world writable files"
+      audittype: "fs_walk"
+      audit:
+        root: "` + root + `"
+        predicates: ["world_writable"]
+        exclude: ["/var/cache"]
+      tests:
+        test_items:
+        - path: "{[*].path}"
+          compare:
+            op: eq
+            value: "/srv/data/shared.txt"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 3, Fail: 1}, summary)
+}
@@ -0,0 +1,170 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TypeHTTP audits HTTP, HTTPS and unix socket endpoints.
+const TypeHTTP = "http"
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	unixScheme         = "unix://"
+)
+
+// HTTPAudit sends a request to an endpoint. A unix socket URL is written as
+// unix://<socket>:<path>, e.g. unix:///var/run/docker.sock:/info.
+type HTTPAudit struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Cert    string            `yaml:"cert"`
+	Key     string            `yaml:"key"`
+	CA      string            `yaml:"ca"`
+	// Insecure skips the verification of the server's certificate
+	Insecure bool   `yaml:"insecure"`
+	Timeout  string `yaml:"timeout"`
+}
+
+// HTTPResponse is the response to an HTTPAudit. A JSON body is returned as
+// a document, any other body as a string.
+type HTTPResponse struct {
+	StatusCode int               `json:"status_code"`
+	Status     string            `json:"status"`
+	Headers    map[string]string `json:"headers"`
+	Body       interface{}       `json:"body"`
+}
+
+// Execute sends the request and returns the response as a single document.
+// Responses with an error status are returned like any other response, so
+// tests can check that a request is rejected.
+func (h *HTTPAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	client, url, err := h.client(targetFrom(customConfig))
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), url, strings.NewReader(h.Body))
+	if err != nil {
+		return auditFailed(fmt.Errorf("failed to create request: %v", err))
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return auditFailed(fmt.Errorf("failed to send request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return auditFailed(fmt.Errorf("failed to read response: %v", err))
+	}
+
+	response := HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Headers:    map[string]string{},
+		Body:       string(body),
+	}
+	for k, v := range resp.Header {
+		response.Headers[k] = strings.Join(v, ", ")
+	}
+	var doc interface{}
+	if json.Unmarshal(body, &doc) == nil {
+		response.Body = doc
+	}
+	return jsonResult(response)
+}
+
+// client returns the client to send the request from the target with, and the
+// URL to send it to.
+func (h *HTTPAudit) client(target *Target) (*http.Client, string, error) {
+	timeout := defaultHTTPTimeout
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid timeout %q: %v", h.Timeout, err)
+		}
+		timeout = d
+	}
+
+	if strings.HasPrefix(h.URL, unixScheme) {
+		socket, path, found := strings.Cut(strings.TrimPrefix(h.URL, unixScheme), ":")
+		if !found {
+			path = "/"
+		}
+		return unixSocketClient(target, target.path(socket), timeout), "http://localhost" + path, nil
+	}
+
+	tlsConfig, err := h.tlsConfig(target)
+	if err != nil {
+		return nil, "", err
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, DialContext: target.dial},
+	}, h.URL, nil
+}
+
+// tlsConfig returns the TLS config of the request, with the CA and client
+// certificate read from the target.
+func (h *HTTPAudit) tlsConfig(target *Target) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: h.Insecure} // nolint: gosec
+
+	if h.CA != "" {
+		pem, err := target.fs().ReadFile(target.path(h.CA))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA %s: %v", h.CA, err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA %s", h.CA)
+		}
+	}
+
+	if h.Cert != "" || h.Key != "" {
+		certPEM, err := target.fs().ReadFile(target.path(h.Cert))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate %s: %v", h.Cert, err)
+		}
+		keyPEM, err := target.fs().ReadFile(target.path(h.Key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key %s: %v", h.Key, err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
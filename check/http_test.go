@@ -0,0 +1,254 @@
+package check
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key to dir.
+func writeTestKeyPair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writeFiles(t, dir, map[string]string{
+		name + ".crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		name + ".key": string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	})
+	return certFile, keyFile
+}
+
+// kubeletHandler rejects anonymous requests, like a kubelet with
+// --anonymous-auth=false.
+var kubeletHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	authenticated := r.Header.Get("Authorization") != "" || (r.TLS != nil && len(r.TLS.PeerCertificates) > 0)
+	if !authenticated {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kubeletconfig": map[string]interface{}{"readOnlyPort": 0},
+		"method":        r.Method,
+	})
+})
+
+func TestHTTPAudit_Execute(t *testing.T) {
+	server := httptest.NewServer(kubeletHandler)
+	defer server.Close()
+	socket := newUnixSocketServer(t, kubeletHandler)
+
+	cases := []struct {
+		name   string
+		audit  HTTPAudit
+		status int
+		body   interface{}
+	}{
+		{
+			name:   "anonymous",
+			audit:  HTTPAudit{URL: server.URL + "/configz"},
+			status: http.StatusUnauthorized,
+			body:   "Unauthorized",
+		},
+		{
+			name:   "headers and method",
+			audit:  HTTPAudit{URL: server.URL + "/configz", Method: "post", Headers: map[string]string{"Authorization": "Bearer token"}},
+			status: http.StatusOK,
+			body:   map[string]interface{}{"kubeletconfig": map[string]interface{}{"readOnlyPort": float64(0)}, "method": "POST"},
+		},
+		{
+			name:   "unix socket",
+			audit:  HTTPAudit{URL: "unix://" + socket + ":/configz", Headers: map[string]string{"Authorization": "Bearer token"}},
+			status: http.StatusOK,
+			body:   map[string]interface{}{"kubeletconfig": map[string]interface{}{"readOnlyPort": float64(0)}, "method": "GET"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute()
+			if errMsg != "" || state != "" {
+				t.Fatalf("unexpected failure: %s %s", state, errMsg)
+			}
+
+			var resp HTTPResponse
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				t.Fatalf("failed to unmarshal %q: %v", out, err)
+			}
+			assert.Equal(t, c.status, resp.StatusCode)
+			assert.Equal(t, c.body, resp.Body)
+			assert.NotEmpty(t, resp.Headers["Content-Type"])
+		})
+	}
+}
+
+func TestHTTPAudit_TLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(kubeletHandler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"ca.crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+	})
+	cert, key := writeTestKeyPair(t, dir, "client")
+
+	cases := []struct {
+		name    string
+		audit   HTTPAudit
+		status  int
+		wantErr bool
+	}{
+		{name: "unknown authority", audit: HTTPAudit{URL: server.URL}, wantErr: true},
+		{name: "insecure", audit: HTTPAudit{URL: server.URL, Insecure: true}, status: http.StatusUnauthorized},
+		{name: "ca", audit: HTTPAudit{URL: server.URL, CA: filepath.Join(dir, "ca.crt")}, status: http.StatusUnauthorized},
+		{name: "client certificate", audit: HTTPAudit{URL: server.URL, CA: filepath.Join(dir, "ca.crt"), Cert: cert, Key: key}, status: http.StatusOK},
+		{name: "missing key", audit: HTTPAudit{URL: server.URL, Cert: cert}, wantErr: true},
+		{name: "invalid timeout", audit: HTTPAudit{URL: server.URL, Timeout: "soon"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute()
+			if c.wantErr {
+				if state != WARN || errMsg == "" {
+					t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+				}
+				return
+			}
+			if errMsg != "" || state != "" {
+				t.Fatalf("unexpected failure: %s %s", state, errMsg)
+			}
+
+			var resp HTTPResponse
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				t.Fatalf("failed to unmarshal %q: %v", out, err)
+			}
+			assert.Equal(t, c.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPAudit_TargetRoot(t *testing.T) {
+	server := httptest.NewUnstartedServer(kubeletHandler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// The CA, client certificate and socket are paths of the target
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/kubernetes/ca.crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+	})
+	writeTestKeyPair(t, filepath.Join(root, "etc/kubernetes"), "client")
+	target := &Target{Root: root}
+
+	audit := HTTPAudit{
+		URL:  server.URL + "/configz",
+		CA:   "/etc/kubernetes/ca.crt",
+		Cert: "/etc/kubernetes/client.crt",
+		Key:  "/etc/kubernetes/client.key",
+	}
+	out, errMsg, state := audit.Execute(target)
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+	var resp HTTPResponse
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("failed to unmarshal %q: %v", out, err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	socket := newUnixSocketServer(t, kubeletHandler)
+	audit = HTTPAudit{URL: "unix:///" + filepath.Base(socket) + ":/configz"}
+	out, errMsg, state = audit.Execute(&Target{Root: filepath.Dir(socket)})
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("failed to unmarshal %q: %v", out, err)
+	}
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHTTPAudit_Check(t *testing.T) {
+	server := httptest.NewServer(kubeletHandler)
+	defer server.Close()
+
+	controls := `---
+controls:
+id: 4
+text: "Worker Node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+    - id: 4.2.1
+      text: "Ensure that anonymous requests to the kubelet are rejected"
+      audittype: "http"
+      audit:
+        url: "` + server.URL + `/configz"
+      tests:
+        test_items:
+        - path: "{.status_code}"
+          compare:
+            op: eq
+            value: 401
+      scored: true
+    - id: 4.2.2
+      text: "Ensure that the read-only port is disabled"
+      audittype: "http"
+      audit:
+        url: "` + server.URL + `/configz"
+        headers:
+          Authorization: "Bearer token"
+      tests:
+        test_items:
+        - path: "{.body.kubeletconfig.readOnlyPort}"
+          compare:
+            op: eq
+            value: 0
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2}, summary)
+}
@@ -0,0 +1,532 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Whiteout files of image layers, see the OCI image layer specification.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// OCI and docker media types of image indexes.
+var imageIndexMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// ociIndex is the index.json of an OCI image layout, or an image index blob.
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is an image manifest blob.
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+// dockerManifest is an entry of the manifest.json of a docker save tarball.
+type dockerManifest struct {
+	Layers []string `json:"Layers"`
+}
+
+// OpenImage returns a target to audit the image or root filesystem at src
+// offline. src is a root filesystem directory, which is audited in place, or
+// an OCI image layout or docker save output, as a directory or a tarball.
+// Images are flattened into a temporary directory in tempDir, or the default
+// directory for temporary files when it is empty, applying the whiteouts of
+// each layer, so the native audit types read the files of the image as they
+// would on a host. Images are extracted rather than mounted, which would
+// require privileges, and the image itself is never modified. Entries under a
+// symlink are refused, as extracting them could change the files of the host
+// the bench runs on. Files keep their owner only when the bench can change it,
+// e.g. as root. Shell and exec audits run in the image with ChrootWrapper,
+// which requires the CAP_SYS_CHROOT capability, e.g. running the bench as
+// root; without it they fail with a WARN. Clear ShellWrapper to report them
+// as not applicable instead. Close the target to remove the flattened image.
+func OpenImage(src, tempDir string) (target *Target, err error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image %s: %v", src, err)
+	}
+
+	var layers []string
+	if info.IsDir() {
+		if layers, err = imageLayers(src); err != nil {
+			return nil, err
+		}
+		if layers == nil {
+			return &Target{Type: TargetRootFS, Root: src, Image: src, ShellWrapper: ChrootWrapper(src)}, nil
+		}
+	}
+
+	dir, err := os.MkdirTemp(tempDir, "image-")
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Join(dir, "rootfs")
+	target = &Target{Type: TargetImage, Root: root, Image: src, ShellWrapper: ChrootWrapper(root), tempDir: dir}
+	defer func() {
+		if err != nil {
+			target.Close() // nolint: errcheck
+			target = nil
+		}
+	}()
+
+	if !info.IsDir() {
+		layout := filepath.Join(dir, "image")
+		if err := extractImageArchive(src, layout); err != nil {
+			return target, err
+		}
+		if layers, err = imageLayers(layout); err != nil {
+			return target, err
+		}
+		if layers == nil {
+			return target, fmt.Errorf("%s is neither an OCI image layout nor a docker save tarball", src)
+		}
+	}
+	return target, flattenLayers(layers, target.Root)
+}
+
+// extractImageArchive extracts the tarball of an image layout, which holds
+// the layers and their manifests.
+func extractImageArchive(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open image %s: %v", src, err)
+	}
+	defer f.Close()
+
+	r, err := decompressLayer(f)
+	if err != nil {
+		return fmt.Errorf("failed to read image %s: %v", src, err)
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read image %s: %v", src, err)
+		}
+		name, ok := layerPath(hdr.Name)
+		if !ok {
+			return fmt.Errorf("invalid path %q in image %s", hdr.Name, src)
+		}
+		target, err := pathInRoot(dst, name)
+		if err != nil {
+			return fmt.Errorf("invalid path %q in image %s: %v", hdr.Name, src, err)
+		}
+		// Later entries replace earlier ones, which are never followed
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return fmt.Errorf("failed to extract %s from image %s: %v", hdr.Name, src, err)
+			}
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeLayerFile(target, tr, 0644)
+		case tar.TypeSymlink:
+			// docker save links layers shared by several images
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+				err = os.Symlink(hdr.Linkname, target)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s from image %s: %v", hdr.Name, src, err)
+		}
+	}
+}
+
+// imageLayers returns the paths of the layer tarballs of the image in the
+// layout directory, from the lowest to the top layer, or nil when the
+// directory is not an image.
+func imageLayers(layout string) ([]string, error) {
+	// docker save writes manifest.json, and also index.json since docker 25
+	data, err := os.ReadFile(filepath.Join(layout, "manifest.json"))
+	if err == nil {
+		var manifests []dockerManifest
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest.json: %v", err)
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("manifest.json has no image")
+		}
+		layers := []string{}
+		for _, layer := range manifests[0].Layers {
+			name, ok := layerPath(layer)
+			if !ok {
+				return nil, fmt.Errorf("invalid layer path %q", layer)
+			}
+			layers = append(layers, filepath.Join(layout, name))
+		}
+		return layers, nil
+	}
+
+	data, err = os.ReadFile(filepath.Join(layout, "index.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index ociIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index.json: %v", err)
+	}
+
+	var manifest ociManifest
+	desc, err := selectManifest(index)
+	for err == nil && contains(imageIndexMediaTypes, desc.MediaType) {
+		var nested ociIndex
+		if err = readBlob(layout, desc.Digest, &nested); err == nil {
+			desc, err = selectManifest(nested)
+		}
+	}
+	if err == nil {
+		err = readBlob(layout, desc.Digest, &manifest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []string{}
+	for _, layer := range manifest.Layers {
+		blob, err := blobPath(layout, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, blob)
+	}
+	return layers, nil
+}
+
+// selectManifest selects the manifest for the platform of the bench, or the
+// first linux manifest, skipping attestations.
+func selectManifest(index ociIndex) (ociDescriptor, error) {
+	var selected *ociDescriptor
+	for i, m := range index.Manifests {
+		if m.Platform == nil {
+			if selected == nil {
+				selected = &index.Manifests[i]
+			}
+			continue
+		}
+		if m.Platform.OS != "linux" {
+			continue
+		}
+		if m.Platform.Architecture == runtime.GOARCH {
+			return m, nil
+		}
+		if selected == nil || selected.Platform == nil {
+			selected = &index.Manifests[i]
+		}
+	}
+	if selected == nil {
+		return ociDescriptor{}, fmt.Errorf("image has no linux manifest")
+	}
+	return *selected, nil
+}
+
+func blobPath(layout, digest string) (string, error) {
+	alg, hex, found := strings.Cut(digest, ":")
+	if !found || alg == "" || hex == "" || strings.ContainsAny(digest, `/\`) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(layout, "blobs", alg, hex), nil
+}
+
+func readBlob(layout, digest string, v interface{}) error {
+	blob, err := blobPath(layout, digest)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(blob)
+	if err != nil {
+		return fmt.Errorf("failed to read blob %s: %v", digest, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse blob %s: %v", digest, err)
+	}
+	return nil
+}
+
+// decompressLayer returns a reader of the tarball of a layer, which is
+// uncompressed or gzip compressed.
+func decompressLayer(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, fmt.Errorf("zstd compressed layers are not supported")
+	}
+	return br, nil
+}
+
+// layerPath cleans the name of a tar entry, which must be in the tarball.
+func layerPath(name string) (string, bool) {
+	clean := path.Clean("/" + name)
+	if clean == "/" {
+		return ".", true
+	}
+	if strings.Contains(name, `\`) {
+		return "", false
+	}
+	// Names starting with .. are cleaned to the root, which is rejected as
+	// they may be meant to escape it
+	for _, element := range strings.Split(name, "/") {
+		if element == ".." {
+			return "", false
+		}
+	}
+	return filepath.FromSlash(clean[1:]), true
+}
+
+// pathInRoot returns the path of name, cleaned by layerPath, under root.
+// Entries under a symlink are refused, as the symlink may point outside of
+// root: extracting them would write, remove or link the files of the host the
+// bench runs on.
+func pathInRoot(root, name string) (string, error) {
+	parent := ""
+	elements := strings.Split(name, string(filepath.Separator))
+	for _, element := range elements[:len(elements)-1] {
+		parent = filepath.Join(parent, element)
+		info, err := os.Lstat(filepath.Join(root, parent))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s is a symlink", filepath.ToSlash(parent))
+		}
+	}
+	return filepath.Join(root, name), nil
+}
+
+// flattenLayers applies the layers, from the lowest, to the root filesystem
+// at root.
+func flattenLayers(layers []string, root string) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	// Directories stay writable until all the layers are applied
+	dirModes := map[string]fs.FileMode{}
+	for _, layer := range layers {
+		if err := applyLayer(layer, root, dirModes); err != nil {
+			return err
+		}
+	}
+
+	dirs := make([]string, 0, len(dirModes))
+	for dir := range dirModes {
+		dirs = append(dirs, dir)
+	}
+	// Children before their parent, in case the parent is not writable
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		// The directory may have been replaced by a symlink of an upper layer
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := os.Chmod(dir, dirModes[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyLayer extracts a layer on the root filesystem at root. Whiteouts
+// remove the files of the lower layers, and opaque whiteouts the content of
+// their directory from the lower layers.
+func applyLayer(layer, root string, dirModes map[string]fs.FileMode) error {
+	f, err := os.Open(layer)
+	if err != nil {
+		return fmt.Errorf("failed to open layer %s: %v", layer, err)
+	}
+	defer f.Close()
+
+	r, err := decompressLayer(f)
+	if err != nil {
+		return fmt.Errorf("failed to read layer %s: %v", layer, err)
+	}
+
+	// added holds the paths of this layer, which opaque whiteouts keep
+	added := map[string]bool{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read layer %s: %v", layer, err)
+		}
+		name, ok := layerPath(hdr.Name)
+		if !ok {
+			return fmt.Errorf("invalid path %q in layer %s", hdr.Name, layer)
+		}
+		target, err := pathInRoot(root, name)
+		if err != nil {
+			return fmt.Errorf("invalid path %q in layer %s: %v", hdr.Name, layer, err)
+		}
+		dir, base := filepath.Split(target)
+
+		switch {
+		case base == whiteoutOpaque:
+			if err := removeLowerEntries(filepath.Clean(dir), added); err != nil {
+				return err
+			}
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			if err := os.RemoveAll(filepath.Join(dir, base[len(whiteoutPrefix):])); err != nil {
+				return err
+			}
+			continue
+		}
+		added[target] = true
+
+		if err := applyLayerEntry(tr, hdr, root, target, dirModes); err != nil {
+			return fmt.Errorf("failed to extract %s from layer %s: %v", hdr.Name, layer, err)
+		}
+	}
+}
+
+// removeLowerEntries removes the content of dir which was not added by the
+// current layer.
+func removeLowerEntries(dir string, added map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if added[p] {
+			if entry.IsDir() {
+				if err := removeLowerEntries(p, added); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyLayerEntry(tr *tar.Reader, hdr *tar.Header, root, target string, dirModes map[string]fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	mode := hdr.FileInfo().Mode()
+
+	// An entry replaces the entry of a lower layer, except a directory
+	// replacing a directory
+	if info, err := os.Lstat(target); err == nil && !(info.IsDir() && hdr.Typeflag == tar.TypeDir) {
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		dirModes[target] = mode.Perm() | mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)
+	case tar.TypeReg:
+		if err := writeLayerFile(target, tr, 0600); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		name, ok := layerPath(hdr.Linkname)
+		if !ok {
+			return fmt.Errorf("invalid link %q", hdr.Linkname)
+		}
+		linked, err := pathInRoot(root, name)
+		if err != nil {
+			return fmt.Errorf("invalid link %q: %v", hdr.Linkname, err)
+		}
+		// Hard links share the owner and mode of the file they link to
+		return os.Link(linked, target)
+	default:
+		// Devices and fifos can't be created without privileges, and
+		// their content is irrelevant
+		return nil
+	}
+
+	// Owners can only be kept when running as root
+	_ = os.Lchown(target, hdr.Uid, hdr.Gid)
+	if hdr.Typeflag == tar.TypeReg {
+		return os.Chmod(target, mode.Perm()|mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+	}
+	return nil
+}
+
+func writeLayerFile(target string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Existing entries are removed first, so a symlink is never followed
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
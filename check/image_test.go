@@ -0,0 +1,420 @@
+package check
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	mode     int64
+	content  string
+	linkname string
+}
+
+func buildTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: e.mode, Size: int64(len(e.content)), Linkname: e.linkname}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write tar header %s: %v", e.name, err)
+		}
+		if e.typeflag != tar.TypeReg {
+			continue
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatalf("failed to write tar entry %s: %v", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testImageLayers are a base layer, and a layer removing files of the base
+// layer with whiteouts.
+func testImageLayers(t *testing.T) [][]byte {
+	base := buildTar(t, []tarEntry{
+		{name: "etc/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "etc/passwd", typeflag: tar.TypeReg, mode: 0644, content: "root:x:0:0:root:/root:/bin/sh\nimage:x:1000:1000::/home/image:/bin/sh\n"},
+		{name: "etc/group", typeflag: tar.TypeReg, mode: 0644, content: "root:x:0:\n"},
+		{name: "etc/old.conf", typeflag: tar.TypeReg, mode: 0644, content: "old"},
+		{name: "tmp/", typeflag: tar.TypeDir, mode: 01777},
+		{name: "usr/bin/su", typeflag: tar.TypeReg, mode: 04755, content: "su"},
+		{name: "opt/app/a", typeflag: tar.TypeReg, mode: 0644, content: "a"},
+		{name: "opt/app/b", typeflag: tar.TypeReg, mode: 0644, content: "b"},
+		{name: "dev/null", typeflag: tar.TypeChar, mode: 0666},
+	})
+	top := buildTar(t, []tarEntry{
+		{name: "etc/.wh.old.conf", typeflag: tar.TypeReg},
+		{name: "opt/app/c", typeflag: tar.TypeReg, mode: 0644, content: "c"},
+		{name: "opt/app/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "usr/bin/passwd", typeflag: tar.TypeLink, linkname: "usr/bin/su"},
+		{name: "etc/passwd-", typeflag: tar.TypeSymlink, linkname: "passwd"},
+		{name: "readonly/", typeflag: tar.TypeDir, mode: 0555},
+		{name: "readonly/file", typeflag: tar.TypeReg, mode: 0444, content: "ro"},
+	})
+	return [][]byte{base, top}
+}
+
+func writeBlob(t *testing.T, layout string, data []byte) string {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	writeFiles(t, layout, map[string]string{filepath.Join("blobs", "sha256", digest): string(data)})
+	return "sha256:" + digest
+}
+
+func writeJSONBlob(t *testing.T, layout string, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode blob: %v", err)
+	}
+	return writeBlob(t, layout, data)
+}
+
+// writeOCILayout writes an OCI image layout with an image index holding the
+// image and an attestation.
+func writeOCILayout(t *testing.T, layout string) {
+	t.Helper()
+	var layers []map[string]string
+	for _, layer := range testImageLayers(t) {
+		layers = append(layers, map[string]string{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"digest":    writeBlob(t, layout, gzipData(t, layer)),
+		})
+	}
+	manifest := writeJSONBlob(t, layout, map[string]interface{}{"schemaVersion": 2, "layers": layers})
+	attestation := writeJSONBlob(t, layout, map[string]interface{}{"schemaVersion": 2, "layers": []interface{}{}})
+	index := writeJSONBlob(t, layout, map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests": []map[string]interface{}{
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": attestation, "platform": map[string]string{"os": "unknown", "architecture": "unknown"}},
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": manifest, "platform": map[string]string{"os": "linux", "architecture": runtime.GOARCH}},
+		},
+	})
+	data, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests":     []map[string]string{{"mediaType": "application/vnd.oci.image.index.v1+json", "digest": index}},
+	})
+	writeFiles(t, layout, map[string]string{"oci-layout": `{"imageLayoutVersion":"1.0.0"}`, "index.json": string(data)})
+}
+
+// writeDockerSave writes a tarball in the format of docker save before
+// docker 25, with a directory for each layer.
+func writeDockerSave(t *testing.T, path string) {
+	t.Helper()
+	layers := testImageLayers(t)
+	manifest, _ := json.Marshal([]map[string]interface{}{{
+		"Config":   "config.json",
+		"RepoTags": []string{"test:latest"},
+		"Layers":   []string{"base/layer.tar", "top/layer.tar"},
+	}})
+	data := buildTar(t, []tarEntry{
+		{name: "base/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "base/layer.tar", typeflag: tar.TypeReg, mode: 0644, content: string(layers[0])},
+		{name: "top/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "top/layer.tar", typeflag: tar.TypeReg, mode: 0644, content: string(layers[1])},
+		{name: "config.json", typeflag: tar.TypeReg, mode: 0644, content: "{}"},
+		{name: "manifest.json", typeflag: tar.TypeReg, mode: 0644, content: string(manifest)},
+	})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func assertFlattenedImage(t *testing.T, root string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, "etc", "passwd"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "image:x:1000")
+
+	assert.NoFileExists(t, filepath.Join(root, "etc", "old.conf"))
+	assert.NoFileExists(t, filepath.Join(root, "opt", "app", "a"))
+	assert.NoFileExists(t, filepath.Join(root, "opt", "app", "b"))
+	assert.FileExists(t, filepath.Join(root, "opt", "app", "c"))
+	assert.NoFileExists(t, filepath.Join(root, "dev", "null"))
+
+	info, err := os.Stat(filepath.Join(root, "usr", "bin", "su"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0755)|os.ModeSetuid, info.Mode())
+	}
+	link, err := os.Stat(filepath.Join(root, "usr", "bin", "passwd"))
+	if assert.NoError(t, err) {
+		assert.True(t, os.SameFile(info, link))
+	}
+	target, err := os.Readlink(filepath.Join(root, "etc", "passwd-"))
+	assert.NoError(t, err)
+	assert.Equal(t, "passwd", target)
+
+	info, err = os.Stat(filepath.Join(root, "tmp"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.ModeDir|os.ModeSticky|0777, info.Mode())
+	}
+	info, err = os.Stat(filepath.Join(root, "readonly"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.ModeDir|0555, info.Mode())
+	}
+}
+
+func TestOpenImage(t *testing.T) {
+	t.Run("oci layout", func(t *testing.T) {
+		layout := t.TempDir()
+		writeOCILayout(t, layout)
+
+		target, err := OpenImage(layout, t.TempDir())
+		if err != nil {
+			t.Fatalf("OpenImage failed: %v", err)
+		}
+		defer target.Close()
+		assert.Equal(t, TargetImage, target.Type)
+		assert.Equal(t, layout, target.Image)
+		assert.Equal(t, ChrootWrapper(target.Root), target.ShellWrapper)
+		assertFlattenedImage(t, target.Root)
+	})
+
+	t.Run("docker save", func(t *testing.T) {
+		tarball := filepath.Join(t.TempDir(), "image.tar")
+		writeDockerSave(t, tarball)
+
+		tempDir := t.TempDir()
+		target, err := OpenImage(tarball, tempDir)
+		if err != nil {
+			t.Fatalf("OpenImage failed: %v", err)
+		}
+		assert.Equal(t, TargetImage, target.Type)
+		assertFlattenedImage(t, target.Root)
+
+		assert.NoError(t, target.Close())
+		entries, err := os.ReadDir(tempDir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("rootfs", func(t *testing.T) {
+		rootfs := t.TempDir()
+		writeFiles(t, rootfs, map[string]string{"etc/passwd": "root:x:0:0:root:/root:/bin/sh\n"})
+
+		target, err := OpenImage(rootfs, t.TempDir())
+		if err != nil {
+			t.Fatalf("OpenImage failed: %v", err)
+		}
+		assert.Equal(t, &Target{Type: TargetRootFS, Root: rootfs, Image: rootfs, ShellWrapper: ChrootWrapper(rootfs)}, target)
+		assert.NoError(t, target.Close())
+		assert.FileExists(t, filepath.Join(rootfs, "etc", "passwd"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		dir := t.TempDir()
+		notImage := filepath.Join(dir, "not-image.tar")
+		if err := os.WriteFile(notImage, buildTar(t, []tarEntry{{name: "file", typeflag: tar.TypeReg, content: "x"}}), 0644); err != nil {
+			t.Fatal(err)
+		}
+		escaping := t.TempDir()
+		writeFiles(t, escaping, map[string]string{"manifest.json": `[{"Layers":["layer.tar"]}]`})
+		layer := buildTar(t, []tarEntry{{name: "../../escaped", typeflag: tar.TypeReg, content: "x"}})
+		if err := os.WriteFile(filepath.Join(escaping, "layer.tar"), layer, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tempDir := t.TempDir()
+		for _, src := range []string{filepath.Join(dir, "missing"), notImage, escaping} {
+			_, err := OpenImage(src, tempDir)
+			assert.Error(t, err, src)
+		}
+		entries, err := os.ReadDir(tempDir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(escaping), "escaped"))
+	})
+}
+
+func TestOpenImage_SymlinkedParents(t *testing.T) {
+	victim := t.TempDir()
+	writeFiles(t, victim, map[string]string{"keep": "keep"})
+	if err := os.Chmod(victim, 0750); err != nil {
+		t.Fatal(err)
+	}
+	escape := tarEntry{name: "etc", typeflag: tar.TypeSymlink, linkname: victim}
+
+	cases := []struct {
+		name   string
+		layers [][]tarEntry
+		err    bool
+	}{
+		{name: "write, then whiteout", err: true, layers: [][]tarEntry{{
+			escape,
+			{name: "etc/written", typeflag: tar.TypeReg, mode: 0644, content: "x"},
+			{name: "etc/.wh.keep", typeflag: tar.TypeReg},
+		}}},
+		{name: "whiteout", err: true, layers: [][]tarEntry{
+			{escape},
+			{{name: "etc/.wh.keep", typeflag: tar.TypeReg}},
+		}},
+		{name: "opaque whiteout", err: true, layers: [][]tarEntry{
+			{escape},
+			{{name: "etc/.wh..wh..opq", typeflag: tar.TypeReg}},
+		}},
+		{name: "hard link", err: true, layers: [][]tarEntry{
+			{escape},
+			{{name: "keep", typeflag: tar.TypeLink, linkname: "etc/keep"}},
+		}},
+		{name: "directory replaced by a symlink", layers: [][]tarEntry{
+			{{name: "etc/", typeflag: tar.TypeDir, mode: 0700}},
+			{escape},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest := dockerManifest{}
+			for i, entries := range c.layers {
+				name := fmt.Sprintf("layer%d.tar", i)
+				if err := os.WriteFile(filepath.Join(dir, name), buildTar(t, entries), 0644); err != nil {
+					t.Fatal(err)
+				}
+				manifest.Layers = append(manifest.Layers, name)
+			}
+			data, _ := json.Marshal([]dockerManifest{manifest})
+			writeFiles(t, dir, map[string]string{"manifest.json": string(data)})
+
+			target, err := OpenImage(dir, t.TempDir())
+			if c.err {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				target.Close()
+			}
+
+			entries, err := os.ReadDir(victim)
+			assert.NoError(t, err)
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, "keep", entries[0].Name())
+			}
+			info, err := os.Lstat(filepath.Join(victim, "keep"))
+			if assert.NoError(t, err) {
+				assert.Equal(t, os.FileMode(0644), info.Mode())
+			}
+			info, err = os.Stat(victim)
+			if assert.NoError(t, err) {
+				assert.Equal(t, os.ModeDir|0750, info.Mode())
+			}
+		})
+	}
+}
+
+func TestImageTarget_Check(t *testing.T) {
+	layout := t.TempDir()
+	writeOCILayout(t, layout)
+	target, err := OpenImage(layout, t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenImage failed: %v", err)
+	}
+	defer target.Close()
+	// Without a shell wrapper, shell audits would run on the host
+	target.ShellWrapper = nil
+
+	controls := `---
+controls:
+id: 1
+text: "Image"
+groups:
+- id: 1.1
+  text: "Image checks"
+  checks:
+    - id: 1.1.1
+      text: "Ensure the image user exists"
+      audittype: "accounts"
+      tests:
+        test_items:
+        - path: "{.users[?(@.name==\"image\")].uid}"
+          compare:
+            op: eq
+            value: 1000
+      scored: true
+    - id: 1.1.2
+      text: "Ensure no process listens on all addresses"
+      audittype: "sockets"
+      tests:
+        test_items:
+        - path: "{.address}"
+          compare:
+            op: noteq
+            value: "0.0.0.0"
+      scored: true
+    - id: 1.1.3
+      text: "Ensure the shell audit is not run on the host"
+      audit: "echo anything"
+      tests:
+        test_items:
+        - flag: "anything"
+      scored: true
+    - id: 1.1.4
+      text: "Ensure the kubelet healthz endpoint is not requested from the host"
+      audittype: "http"
+      audit:
+        url: "http://localhost:10248/healthz"
+      tests:
+        test_items:
+        - path: "{.status}"
+          compare:
+            op: eq
+            value: 200
+      scored: true
+    - id: 1.1.5
+      text: "Ensure the loaded audit rules are not read from the host"
+      audittype: "audit_rules"
+      audit:
+        source: loaded
+      tests:
+        test_items:
+        - path: "{.key}"
+          compare:
+            op: eq
+            value: "identity"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil, target)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+	assert.Equal(t, &TargetMetadata{Type: TargetImage, Image: layout}, c.Target)
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, NotApplicable: 4}, summary)
+	for _, check := range c.Groups[0].Checks[1:] {
+		assert.EqualValues(t, NOTAPPLICABLE, check.State, check.ID)
+		assert.Equal(t, "Not applicable to image targets", check.Reason, check.ID)
+	}
+}
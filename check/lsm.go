@@ -0,0 +1,268 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeLSM audits the Linux security modules and the confinement of processes.
+const TypeLSM = "lsm"
+
+var (
+	// apparmorLabel is an AppArmor profile with its mode, e.g. "docker-default (enforce)"
+	apparmorLabel = regexp.MustCompile(`^(.+) \(([a-z]+)\)$`)
+	seccompModes  = map[string]string{"0": "disabled", "1": "strict", "2": "filter"}
+)
+
+// LSMAudit reads the status of AppArmor and SELinux from /sys/kernel/security
+// and /sys/fs/selinux, and the security context and seccomp mode of processes
+// from /proc/<pid>/attr/current and /proc/<pid>/status.
+type LSMAudit struct {
+	// Processes are command names of processes to report, e.g. "dockerd"
+	Processes []string `yaml:"processes"`
+	PIDs      []int    `yaml:"pids"`
+	SysRoot   string   `yaml:"sys_root"`
+	ProcRoot  string   `yaml:"proc_root"`
+}
+
+// LSMStatus is the status of the Linux security modules.
+type LSMStatus struct {
+	// Modules are the active security modules, in the order they are called
+	Modules   []string        `json:"modules"`
+	AppArmor  AppArmorStatus  `json:"apparmor"`
+	SELinux   SELinuxStatus   `json:"selinux"`
+	Processes []ProcessStatus `json:"processes"`
+}
+
+// AppArmorStatus is the status of AppArmor and its loaded profiles.
+type AppArmorStatus struct {
+	Enabled       bool              `json:"enabled"`
+	Profiles      []AppArmorProfile `json:"profiles"`
+	EnforceCount  int               `json:"enforce_count"`
+	ComplainCount int               `json:"complain_count"`
+}
+
+// AppArmorProfile is a loaded AppArmor profile.
+type AppArmorProfile struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+}
+
+// SELinuxStatus is the status of SELinux.
+type SELinuxStatus struct {
+	Enabled       bool   `json:"enabled"`
+	Enforcing     bool   `json:"enforcing"`
+	Mode          string `json:"mode"`
+	PolicyVersion int    `json:"policy_version,omitempty"`
+	MLS           bool   `json:"mls"`
+}
+
+// ProcessStatus is the confinement of a process.
+type ProcessStatus struct {
+	PID             int    `json:"pid"`
+	Command         string `json:"command"`
+	Context         string `json:"context"`
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
+	AppArmorMode    string `json:"apparmor_mode,omitempty"`
+	SELinuxType     string `json:"selinux_type,omitempty"`
+	Seccomp         string `json:"seccomp"`
+	SeccompFilters  int    `json:"seccomp_filters"`
+	NoNewPrivs      bool   `json:"no_new_privs"`
+}
+
+// Execute returns the status as a single document.
+func (l *LSMAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	sysRoot := target.path(rootOrDefault(l.SysRoot, "/sys"))
+	procRoot := target.path(rootOrDefault(l.ProcRoot, "/proc"))
+	fsys := target.fs()
+
+	status := LSMStatus{
+		Modules:   []string{},
+		AppArmor:  readAppArmorStatus(fsys, sysRoot),
+		SELinux:   readSELinuxStatus(fsys, sysRoot),
+		Processes: []ProcessStatus{},
+	}
+	if lsm, err := fsys.ReadFile(filepath.Join(sysRoot, "kernel", "security", "lsm")); err == nil {
+		status.Modules = splitList(strings.TrimSpace(string(lsm)))
+	}
+
+	pids, err := l.pids(fsys, procRoot)
+	if err != nil {
+		return auditFailed(err)
+	}
+	for _, pid := range pids {
+		process, err := readProcessStatus(fsys, procRoot, pid)
+		// The process exited since it was listed
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return auditFailed(err)
+		}
+		status.Processes = append(status.Processes, *process)
+	}
+	return jsonResult(status)
+}
+
+// pids returns the configured PIDs and those of the configured processes.
+func (l *LSMAudit) pids(fsys targetFS, procRoot string) ([]int, error) {
+	pids := append([]int{}, l.PIDs...)
+	if len(l.Processes) == 0 {
+		return pids, nil
+	}
+
+	entries, err := fsys.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", procRoot, err)
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		comm, err := fsys.ReadFile(filepath.Join(procRoot, entry.Name(), "comm"))
+		if err == nil && contains(l.Processes, strings.TrimSpace(string(comm))) && !containsInt(pids, pid) {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	return pids, nil
+}
+
+func readAppArmorStatus(fsys targetFS, sysRoot string) AppArmorStatus {
+	status := AppArmorStatus{Profiles: []AppArmorProfile{}}
+	if enabled, err := fsys.ReadFile(filepath.Join(sysRoot, "module", "apparmor", "parameters", "enabled")); err == nil {
+		status.Enabled = strings.TrimSpace(string(enabled)) == "Y"
+	}
+
+	profiles, err := fsys.ReadFile(filepath.Join(sysRoot, "kernel", "security", "apparmor", "profiles"))
+	if err != nil {
+		return status
+	}
+	status.Enabled = true
+	scanner := bufio.NewScanner(bytes.NewReader(profiles))
+	for scanner.Scan() {
+		m := apparmorLabel.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		status.Profiles = append(status.Profiles, AppArmorProfile{Name: m[1], Mode: m[2]})
+		switch m[2] {
+		case "enforce":
+			status.EnforceCount++
+		case "complain":
+			status.ComplainCount++
+		}
+	}
+	return status
+}
+
+func readSELinuxStatus(fsys targetFS, sysRoot string) SELinuxStatus {
+	status := SELinuxStatus{Mode: "disabled"}
+	selinuxfs := filepath.Join(sysRoot, "fs", "selinux")
+	enforce, err := fsys.ReadFile(filepath.Join(selinuxfs, "enforce"))
+	if err != nil {
+		return status
+	}
+	status.Enabled = true
+	status.Enforcing = strings.TrimSpace(string(enforce)) == "1"
+	status.Mode = "permissive"
+	if status.Enforcing {
+		status.Mode = "enforcing"
+	}
+	if vers, err := fsys.ReadFile(filepath.Join(selinuxfs, "policyvers")); err == nil {
+		status.PolicyVersion, _ = strconv.Atoi(strings.TrimSpace(string(vers)))
+	}
+	if mls, err := fsys.ReadFile(filepath.Join(selinuxfs, "mls")); err == nil {
+		status.MLS = strings.TrimSpace(string(mls)) == "1"
+	}
+	return status
+}
+
+func readProcessStatus(fsys targetFS, procRoot string, pid int) (*ProcessStatus, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	data, err := fsys.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status of process %d: %w", pid, err)
+	}
+
+	process := &ProcessStatus{PID: pid, Seccomp: seccompModes["0"]}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			process.Command = value
+		case "Seccomp":
+			if mode, ok := seccompModes[value]; ok {
+				process.Seccomp = mode
+			}
+		case "Seccomp_filters":
+			process.SeccompFilters, _ = strconv.Atoi(value)
+		case "NoNewPrivs":
+			process.NoNewPrivs = value == "1"
+		}
+	}
+
+	// Kernels with LSM stacking have the AppArmor label in attr/apparmor
+	for _, attr := range []string{filepath.Join("attr", "apparmor", "current"), filepath.Join("attr", "current")} {
+		label, err := fsys.ReadFile(filepath.Join(dir, attr))
+		if err == nil {
+			process.Context = strings.TrimSpace(strings.TrimRight(string(label), "\x00"))
+			break
+		}
+	}
+	process.parseContext()
+	return process, nil
+}
+
+// parseContext fills in the AppArmor profile and mode, or the SELinux type,
+// from the security context of the process.
+func (p *ProcessStatus) parseContext() {
+	switch {
+	case p.Context == "unconfined":
+		p.AppArmorProfile, p.AppArmorMode = "unconfined", "unconfined"
+	case apparmorLabel.MatchString(p.Context):
+		m := apparmorLabel.FindStringSubmatch(p.Context)
+		p.AppArmorProfile, p.AppArmorMode = m[1], m[2]
+	case strings.Count(p.Context, ":") >= 2:
+		// user:role:type[:level]
+		p.SELinuxType = strings.Split(p.Context, ":")[2]
+	}
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
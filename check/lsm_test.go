@@ -0,0 +1,195 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func processStatus(name string, pid, noNewPrivs, seccomp, filters int) string {
+	return fmt.Sprintf("Name:\t%s\nUmask:\t0022\nState:\tS (sleeping)\nPid:\t%d\n"+
+		"NoNewPrivs:\t%d\nSeccomp:\t%d\nSeccomp_filters:\t%d\n", name, pid, noNewPrivs, seccomp, filters)
+}
+
+// writeLSMTree creates fake /sys and /proc trees with AppArmor enabled.
+func writeLSMTree(t *testing.T) (sysRoot, procRoot string) {
+	t.Helper()
+	sysRoot, procRoot = t.TempDir(), t.TempDir()
+	writeFiles(t, sysRoot, map[string]string{
+		"kernel/security/lsm":                "lockdown,capability,landlock,yama,apparmor",
+		"module/apparmor/parameters/enabled": "Y\n",
+		"kernel/security/apparmor/profiles": "docker-default (enforce)\n" +
+			"/usr/sbin/tcpdump (enforce)\n" +
+			"/usr/bin/man (complain)\n",
+	})
+	writeFiles(t, procRoot, map[string]string{
+		"1/comm":          "systemd\n",
+		"1/status":        processStatus("systemd", 1, 0, 0, 0),
+		"1/attr/current":  "unconfined\n",
+		"42/comm":         "dockerd\n",
+		"42/status":       processStatus("dockerd", 42, 0, 0, 0),
+		"42/attr/current": "unconfined\n",
+		"77/comm":         "nginx\n",
+		"77/status":       processStatus("nginx", 77, 1, 2, 1),
+		// attr/apparmor/current takes precedence over attr/current
+		"77/attr/current":          "system_u:system_r:container_t:s0\n",
+		"77/attr/apparmor/current": "docker-default (enforce)\n",
+		"78/comm":                  "nginx\n",
+		"78/status":                processStatus("nginx", 78, 0, 0, 0),
+		"78/attr/current":          "system_u:system_r:container_t:s0:c1,c2\n",
+	})
+	return sysRoot, procRoot
+}
+
+func TestLSMAudit_Execute(t *testing.T) {
+	sysRoot, procRoot := writeLSMTree(t)
+
+	audit := LSMAudit{SysRoot: sysRoot, ProcRoot: procRoot, Processes: []string{"nginx"}, PIDs: []int{1}}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	status := parseRows[LSMStatus](t, out)[0]
+	assert.Equal(t, []string{"lockdown", "capability", "landlock", "yama", "apparmor"}, status.Modules)
+
+	assert.True(t, status.AppArmor.Enabled)
+	assert.Equal(t, 2, status.AppArmor.EnforceCount)
+	assert.Equal(t, 1, status.AppArmor.ComplainCount)
+	assert.Contains(t, status.AppArmor.Profiles, AppArmorProfile{Name: "/usr/bin/man", Mode: "complain"})
+
+	assert.Equal(t, SELinuxStatus{Mode: "disabled"}, status.SELinux)
+
+	assert.Equal(t, []ProcessStatus{
+		{PID: 1, Command: "systemd", Context: "unconfined", AppArmorProfile: "unconfined", AppArmorMode: "unconfined", Seccomp: "disabled"},
+		{PID: 77, Command: "nginx", Context: "docker-default (enforce)", AppArmorProfile: "docker-default", AppArmorMode: "enforce",
+			Seccomp: "filter", SeccompFilters: 1, NoNewPrivs: true},
+		{PID: 78, Command: "nginx", Context: "system_u:system_r:container_t:s0:c1,c2", SELinuxType: "container_t", Seccomp: "disabled"},
+	}, status.Processes)
+}
+
+func TestLSMAudit_SELinux(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  SELinuxStatus
+	}{
+		{
+			name:  "enforcing",
+			files: map[string]string{"fs/selinux/enforce": "1", "fs/selinux/policyvers": "33\n", "fs/selinux/mls": "1"},
+			want:  SELinuxStatus{Enabled: true, Enforcing: true, Mode: "enforcing", PolicyVersion: 33, MLS: true},
+		},
+		{
+			name:  "permissive",
+			files: map[string]string{"fs/selinux/enforce": "0"},
+			want:  SELinuxStatus{Enabled: true, Mode: "permissive"},
+		},
+		{
+			name:  "disabled",
+			files: map[string]string{"kernel/security/lsm": "capability,yama"},
+			want:  SELinuxStatus{Mode: "disabled"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sysRoot := t.TempDir()
+			writeFiles(t, sysRoot, c.files)
+			assert.Equal(t, c.want, readSELinuxStatus(localFS{}, sysRoot))
+			assert.False(t, readAppArmorStatus(localFS{}, sysRoot).Enabled)
+		})
+	}
+}
+
+func TestLSMAudit_MissingProcess(t *testing.T) {
+	sysRoot, procRoot := writeLSMTree(t)
+	// 79 exits after it was found by its name
+	writeFiles(t, procRoot, map[string]string{"79/comm": "nginx\n"})
+
+	// Processes which exited are left out
+	audit := LSMAudit{SysRoot: sysRoot, ProcRoot: procRoot, Processes: []string{"nginx"}, PIDs: []int{999}}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	var pids []int
+	for _, process := range parseRows[LSMStatus](t, out)[0].Processes {
+		pids = append(pids, process.PID)
+	}
+	assert.Equal(t, []int{77, 78}, pids)
+
+	// Other failures to read the status fail the audit
+	if err := os.MkdirAll(filepath.Join(procRoot, "80", "status"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	audit = LSMAudit{SysRoot: sysRoot, ProcRoot: procRoot, PIDs: []int{80}}
+	_, errMsg, state = audit.Execute()
+	assert.NotEmpty(t, errMsg)
+	assert.EqualValues(t, WARN, state)
+}
+
+func TestLSMAudit_Check(t *testing.T) {
+	sysRoot, procRoot := writeLSMTree(t)
+
+	controls := `---
+controls:
+id: 5
+text: "Container Runtime"
+groups:
+- id: 5.1
+  text: "Container Runtime"
+  checks:
+    - id: 5.1.1
+      text: "Ensure that, if applicable, an AppArmor Profile is enabled"
+      audittype: "lsm"
+      audit:
+        sys_root: "` + sysRoot + `"
+        proc_root: "` + procRoot + `"
+      tests:
+        bin_op: and
+        test_items:
+        - path: "{.apparmor.enabled}"
+          compare:
+            op: eq
+            value: true
+        - path: "{.apparmor.enforce_count}"
+          compare:
+            op: gt
+            value: 0
+      scored: true
+    - id: 5.1.2
+      text: "Ensure that SELinux is enforcing"
+      audittype: "lsm"
+      audit:
+        sys_root: "` + sysRoot + `"
+        proc_root: "` + procRoot + `"
+      tests:
+        test_items:
+        - path: "{.selinux.mode}"
+          compare:
+            op: eq
+            value: enforcing
+      scored: true
+    - id: 5.1.3
+      text: "Ensure that the default seccomp profile is not disabled"
+      audittype: "lsm"
+      audit:
+        sys_root: "` + sysRoot + `"
+        proc_root: "` + procRoot + `"
+        pids: [77]
+      tests:
+        test_items:
+        - path: "{.processes[0].seccomp}"
+          compare:
+            op: eq
+            value: filter
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2, Fail: 1}, summary)
+}
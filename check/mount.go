@@ -0,0 +1,207 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TypeMount audits mount points and their options.
+const TypeMount = "mount"
+
+// MountAudit reads the current mounts from /proc/self/mountinfo, or
+// /proc/1/mountinfo on targets other than the host the bench runs on, and the
+// configured mounts from /etc/fstab.
+type MountAudit struct {
+	MountPoints []string `yaml:"mount_points"`
+	ProcRoot    string   `yaml:"proc_root"`
+	EtcRoot     string   `yaml:"etc_root"`
+}
+
+// MountEntry is a single mount, either current or configured.
+type MountEntry struct {
+	Source       string   `json:"source"`
+	FSType       string   `json:"fs_type"`
+	Options      []string `json:"options"`
+	SuperOptions []string `json:"super_options,omitempty"`
+}
+
+// MountPoint holds what is currently mounted at a mount point and what is
+// configured for it in fstab.
+type MountPoint struct {
+	MountPoint string      `json:"mount_point"`
+	Mounted    bool        `json:"mounted"`
+	Configured bool        `json:"configured"`
+	Mount      *MountEntry `json:"mount,omitempty"`
+	Fstab      *MountEntry `json:"fstab,omitempty"`
+}
+
+// Execute returns a row for each of the configured mount points, or for every
+// mount point found when none are configured.
+func (m *MountAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	mountinfo := mountInfoPath(target, m.ProcRoot)
+	data, err := target.fs().ReadFile(mountinfo)
+	if err != nil {
+		return auditFailed(fmt.Errorf("failed to read %s: %v", mountinfo, err))
+	}
+	mounts, mountOrder := parseMountInfo(data)
+
+	fstab := filepath.Join(target.path(rootOrDefault(m.EtcRoot, "/etc")), "fstab")
+	data, err = target.fs().ReadFile(fstab)
+	if err != nil && !os.IsNotExist(err) {
+		return auditFailed(fmt.Errorf("failed to read %s: %v", fstab, err))
+	}
+	configured, fstabOrder := parseFstab(data)
+
+	mountPoints := m.MountPoints
+	if len(mountPoints) == 0 {
+		mountPoints = mountOrder
+		for _, mp := range fstabOrder {
+			if _, ok := mounts[mp]; !ok {
+				mountPoints = append(mountPoints, mp)
+			}
+		}
+	}
+
+	rows := make([]MountPoint, 0, len(mountPoints))
+	for _, mp := range mountPoints {
+		mp = path.Clean(mp)
+		row := MountPoint{MountPoint: mp, Mount: mounts[mp], Fstab: configured[mp]}
+		row.Mounted = row.Mount != nil
+		row.Configured = row.Fstab != nil
+		rows = append(rows, row)
+	}
+
+	return jsonLinesResult(rows)
+}
+
+// mountInfoPath returns the path of the mountinfo of the target. The mounts of
+// the bench are those of the target on the host the bench runs on only. On
+// other targets, e.g. the node's filesystem mounted at /host, the bench runs
+// in another mount namespace, so the mounts of init are read instead.
+func mountInfoPath(target *Target, procRoot string) string {
+	pid := "1"
+	if target.ssh == nil && (target.Root == "" || target.Root == "/") {
+		pid = "self"
+	}
+	return filepath.Join(target.path(rootOrDefault(procRoot, "/proc")), pid, "mountinfo")
+}
+
+// parseMountInfo parses the proc(5) mountinfo format, for example
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//
+// When a mount point is mounted over, the last (visible) mount is kept.
+func parseMountInfo(data []byte) (map[string]*MountEntry, []string) {
+	mounts := map[string]*MountEntry{}
+	var order []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || len(fields) < sep+3 {
+			continue
+		}
+
+		mp := path.Clean(unescapeMountField(fields[4]))
+		entry := &MountEntry{
+			Source:  unescapeMountField(fields[sep+2]),
+			FSType:  fields[sep+1],
+			Options: strings.Split(fields[5], ","),
+		}
+		if len(fields) > sep+3 {
+			entry.SuperOptions = strings.Split(fields[sep+3], ",")
+		}
+
+		if _, ok := mounts[mp]; !ok {
+			order = append(order, mp)
+		}
+		mounts[mp] = entry
+	}
+	return mounts, order
+}
+
+// parseFstab parses the fstab(5) format. Swap entries have no mount point
+// and are ignored.
+func parseFstab(data []byte) (map[string]*MountEntry, []string) {
+	entries := map[string]*MountEntry{}
+	var order []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] == "swap" || !strings.HasPrefix(fields[1], "/") {
+			continue
+		}
+
+		mp := path.Clean(unescapeMountField(fields[1]))
+		entry := &MountEntry{
+			Source:  unescapeMountField(fields[0]),
+			FSType:  fields[2],
+			Options: []string{"defaults"},
+		}
+		if len(fields) > 3 {
+			entry.Options = strings.Split(fields[3], ",")
+		}
+
+		if _, ok := entries[mp]; !ok {
+			order = append(order, mp)
+		}
+		entries[mp] = entry
+	}
+	return entries, order
+}
+
+// unescapeMountField decodes the octal escapes used for whitespace and
+// backslashes in mountinfo and fstab, e.g. "\040" for a space.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
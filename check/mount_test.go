@@ -0,0 +1,170 @@
+package check
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+25 22 0:21 / /dev/shm rw,nosuid,nodev shared:3 - tmpfs tmpfs rw,inode64
+30 22 0:26 / /tmp rw,relatime shared:8 - tmpfs tmpfs rw,size=1024k
+31 30 0:27 / /tmp rw,nosuid,nodev,noexec,relatime shared:9 - tmpfs tmpfs rw,size=2048k
+40 22 8:2 / /mnt/my\040data rw,relatime - ext4 /dev/sda2 rw
+`
+
+const testFstab = `# /etc/fstab: static file system information.
+UUID=1234 /               ext4    errors=remount-ro 0       1
+tmpfs     /tmp            tmpfs   defaults,rw,nosuid,nodev,noexec 0 0
+/dev/sda3 /home           ext4    nodev
+/swapfile none            swap    sw              0       0
+`
+
+func TestMountAudit_Execute(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/mountinfo": testMountInfo,
+		"etc/fstab":           testFstab,
+	})
+
+	audit := &MountAudit{
+		MountPoints: []string{"/tmp", "/home", "/dev/shm/", "/var/tmp"},
+		ProcRoot:    filepath.Join(root, "proc"),
+		EtcRoot:     filepath.Join(root, "etc"),
+	}
+
+	out, errMsg, state := audit.Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	rows := parseRows[MountPoint](t, out)
+	expected := []MountPoint{
+		{
+			MountPoint: "/tmp",
+			Mounted:    true,
+			Configured: true,
+			Mount:      &MountEntry{Source: "tmpfs", FSType: "tmpfs", Options: []string{"rw", "nosuid", "nodev", "noexec", "relatime"}, SuperOptions: []string{"rw", "size=2048k"}},
+			Fstab:      &MountEntry{Source: "tmpfs", FSType: "tmpfs", Options: []string{"defaults", "rw", "nosuid", "nodev", "noexec"}},
+		},
+		{
+			MountPoint: "/home",
+			Configured: true,
+			Fstab:      &MountEntry{Source: "/dev/sda3", FSType: "ext4", Options: []string{"nodev"}},
+		},
+		{
+			MountPoint: "/dev/shm",
+			Mounted:    true,
+			Mount:      &MountEntry{Source: "tmpfs", FSType: "tmpfs", Options: []string{"rw", "nosuid", "nodev"}, SuperOptions: []string{"rw", "inode64"}},
+		},
+		{
+			MountPoint: "/var/tmp",
+		},
+	}
+	assert.Equal(t, expected, rows)
+}
+
+func TestMountAudit_AllMountPoints(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/mountinfo": testMountInfo,
+		"etc/fstab":           testFstab,
+	})
+
+	audit := &MountAudit{ProcRoot: filepath.Join(root, "proc"), EtcRoot: filepath.Join(root, "etc")}
+	out, errMsg, state := audit.Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	var mountPoints []string
+	for _, row := range parseRows[MountPoint](t, out) {
+		mountPoints = append(mountPoints, row.MountPoint)
+	}
+	assert.Equal(t, []string{"/", "/dev/shm", "/tmp", "/mnt/my data", "/home"}, mountPoints)
+}
+
+func TestMountAudit_TargetRoot(t *testing.T) {
+	// The mounts of the target are those of its init, not of the bench
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/mountinfo": "",
+		"proc/1/mountinfo":    testMountInfo,
+		"etc/fstab":           testFstab,
+	})
+
+	out, errMsg, state := (&MountAudit{MountPoints: []string{"/tmp"}}).Execute(&Target{Root: root})
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+	rows := parseRows[MountPoint](t, out)
+	if assert.Len(t, rows, 1) {
+		assert.True(t, rows[0].Mounted)
+	}
+}
+
+func TestMountAudit_MissingMountInfo(t *testing.T) {
+	audit := &MountAudit{ProcRoot: t.TempDir(), EtcRoot: t.TempDir()}
+	_, errMsg, state := audit.Execute()
+	if state != WARN || errMsg == "" {
+		t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+	}
+}
+
+func TestMountAudit_Check(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/mountinfo": testMountInfo,
+		"etc/fstab":           testFstab,
+	})
+
+	controls := `---
+controls:
+id: 1
+text: "Filesystem"
+groups:
+- id: 1.1
+  text: "Filesystem configuration"
+  checks:
+    - id: 1.1.1
+      text: "Ensure nodev option set on /tmp partition"
+      audittype: "mount"
+      audit:
+        mount_points: ["/tmp"]
+        proc_root: "` + filepath.Join(root, "proc") + `"
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{.mount.options}"
+          compare:
+            op: has
+            value: nodev
+        - path: "{.fstab.options}"
+          compare:
+            op: has
+            value: nodev
+      scored: true
+    - id: 1.1.2
+      text: "Ensure separate partition exists for /var/tmp"
+      audittype: "mount"
+      audit:
+        mount_points: ["/var/tmp"]
+        proc_root: "` + filepath.Join(root, "proc") + `"
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{.mounted}"
+          compare:
+            op: eq
+            value: true
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, summary)
+}
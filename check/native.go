@@ -0,0 +1,131 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/bench-common/auditeval"
+)
+
+// Native audit types read the system state directly instead of running a shell
+// command. Their result is JSON, so it can be evaluated by `path` tests. Audit
+// types which return a collection emit one JSON document per line, so every row
+// can be evaluated on its own with use_multiple_values.
+
+// RowsAuditer is implemented by the audit types which emit one JSON document
+// per row. Unless use_multiple_values tests each row on its own, path tests
+// evaluate the rows as a list, e.g. {[*].name}, whether there are none, one
+// or more.
+type RowsAuditer interface {
+	Auditer
+	// EmitsRows tests if the audit, with its settings, emits rows.
+	EmitsRows() bool
+}
+
+func (*SysctlAudit) EmitsRows() bool     { return true }
+func (*MountAudit) EmitsRows() bool      { return true }
+func (*SocketsAudit) EmitsRows() bool    { return true }
+func (*AuditRulesAudit) EmitsRows() bool { return true }
+func (*PackagesAudit) EmitsRows() bool   { return true }
+
+// EmitsRows tests if the matching files are returned, rather than the
+// summary.
+func (w *FSWalkAudit) EmitsRows() bool {
+	return !w.Summary
+}
+
+// EmitsRows tests if the query returns containers, networks or images, rather
+// than a single document.
+func (d *DockerAudit) EmitsRows() bool {
+	return d.Query != dockerInfo && d.Query != dockerVersion
+}
+
+// executeTests evaluates tests against the result of auditer. path tests
+// evaluate the rows of the audit types which emit them as a list.
+func executeTests(tests *auditeval.Tests, auditer Auditer, result AuditResult, id string, isMultiple bool) *auditeval.TestOutput {
+	if a, ok := auditer.(RowsAuditer); ok && a.EmitsRows() {
+		return tests.ExecuteJSONLines(result.Output, result.sources(), id, isMultiple)
+	}
+	return tests.ExecuteSources(result.Output, result.sources(), id, isMultiple)
+}
+
+// jsonLines encodes each of the rows as a single line of JSON.
+func jsonLines[T any](rows []T) (string, error) {
+	var sb strings.Builder
+	for _, row := range rows {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return "", err
+		}
+		sb.Write(b)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// jsonResult encodes a single document as the result of a native audit.
+func jsonResult(v interface{}) (result string, errMessage string, state State) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return auditFailed(err)
+	}
+	return string(b), "", ""
+}
+
+// jsonLinesResult encodes rows as the result of a native audit.
+func jsonLinesResult[T any](rows []T) (result string, errMessage string, state State) {
+	out, err := jsonLines(rows)
+	if err != nil {
+		return auditFailed(err)
+	}
+	return out, "", ""
+}
+
+// auditFailed reports an audit which could not be carried out.
+func auditFailed(err error) (result string, errMessage string, state State) {
+	return "", err.Error(), WARN
+}
+
+// rootOrDefault returns root, or def when root was not configured.
+func rootOrDefault(root, def string) string {
+	return orDefault(root, def)
+}
+
+// orDefault returns s, or def when s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// unixSocketClient returns an HTTP client which connects to socket in the
+// target, whatever the host of the request URL.
+func unixSocketClient(target *Target, socket string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return target.dial(ctx, "unix", socket)
+			},
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package check
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files, keyed by their path relative to root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// newUnixSocketServer serves handler on a unix socket in a temporary directory.
+func newUnixSocketServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "test.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", socket, err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+// parseRows decodes the rows returned by a native audit.
+func parseRows[T any](t *testing.T, out string) []T {
+	t.Helper()
+	var rows []T
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var row T
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("failed to unmarshal %q: %v", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestJSONLines(t *testing.T) {
+	type row struct {
+		Name string `json:"name"`
+	}
+
+	cases := []struct {
+		rows []row
+		want string
+	}{
+		{rows: nil, want: ""},
+		{rows: []row{{Name: "a"}}, want: "{\"name\":\"a\"}\n"},
+		{rows: []row{{Name: "a"}, {Name: "b"}}, want: "{\"name\":\"a\"}\n{\"name\":\"b\"}\n"},
+	}
+
+	for i, c := range cases {
+		got, err := jsonLines(c.rows)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error %v", i, err)
+		}
+		if got != c.want {
+			t.Errorf("case %d: expected %q, got %q", i, c.want, got)
+		}
+	}
+}
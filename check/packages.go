@@ -0,0 +1,285 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TypePackages audits the installed packages.
+const TypePackages = "packages"
+
+// Package ecosystems
+const (
+	ecosystemDpkg = "dpkg"
+	ecosystemApk  = "apk"
+	ecosystemRpm  = "rpm"
+)
+
+var (
+	dpkgStatusFile  = filepath.Join("var", "lib", "dpkg", "status")
+	apkInstalledDB  = filepath.Join("lib", "apk", "db", "installed")
+	rpmDBPaths      = []string{filepath.Join("var", "lib", "rpm"), filepath.Join("usr", "lib", "sysimage", "rpm")}
+	rpmQueryFormat  = `%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\n`
+	versionOperator = []string{">=", "<=", "!=", "==", "=", ">", "<"}
+)
+
+// PackagesAudit reads the installed packages from the dpkg status file, the
+// apk installed database and the rpm database. The rpm database is read with
+// the rpm command, as its format depends on the rpm version.
+type PackagesAudit struct {
+	// Names returns a row for each of the packages, installed or not,
+	// instead of a row for each installed package.
+	Names []string `yaml:"names"`
+	// Version is a constraint on the version of the packages, e.g.
+	// ">= 1:8.9p1, < 1:9". Versions are compared following the rules of the
+	// package's ecosystem.
+	Version string `yaml:"version"`
+	Root    string `yaml:"root"`
+	Rpm     string `yaml:"rpm"`
+}
+
+// Package is an installed package.
+type Package struct {
+	Name           string `json:"name"`
+	Installed      bool   `json:"installed"`
+	Version        string `json:"version,omitempty"`
+	Arch           string `json:"arch,omitempty"`
+	Ecosystem      string `json:"ecosystem,omitempty"`
+	VersionMatches *bool  `json:"version_matches,omitempty"`
+}
+
+// Execute returns a row for each package.
+func (p *PackagesAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	constraints, err := parseVersionConstraints(p.Version)
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	target := targetFrom(customConfig)
+	packages, err := p.installed(target, target.path(rootOrDefault(p.Root, "/")))
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	if len(p.Names) > 0 {
+		var selected []Package
+		for _, name := range p.Names {
+			found := false
+			for _, pkg := range packages {
+				if pkg.Name == name {
+					selected = append(selected, pkg)
+					found = true
+				}
+			}
+			if !found {
+				selected = append(selected, Package{Name: name})
+			}
+		}
+		packages = selected
+	}
+
+	if len(constraints) > 0 {
+		for i := range packages {
+			if packages[i].Installed {
+				matches := constraints.match(packages[i].Ecosystem, packages[i].Version)
+				packages[i].VersionMatches = &matches
+			}
+		}
+	}
+	return jsonLinesResult(packages)
+}
+
+// installed reads the packages from each of the package databases found.
+func (p *PackagesAudit) installed(target *Target, root string) ([]Package, error) {
+	var packages []Package
+	fsys := target.fs()
+
+	data, err := fsys.ReadFile(filepath.Join(root, dpkgStatusFile))
+	if err == nil {
+		packages = append(packages, parseDpkgStatus(data)...)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read dpkg status: %v", err)
+	}
+
+	data, err = fsys.ReadFile(filepath.Join(root, apkInstalledDB))
+	if err == nil {
+		packages = append(packages, parseApkInstalled(data)...)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read apk database: %v", err)
+	}
+
+	for _, dbPath := range rpmDBPaths {
+		entries, err := fsys.ReadDir(filepath.Join(root, dbPath))
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		rpm := orDefault(p.Rpm, "rpm")
+		out, err := target.output(rpm, "--root", root, "--dbpath", "/"+filepath.ToSlash(dbPath), "-qa", "--qf", rpmQueryFormat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query rpm database %s with %s: %v", dbPath, rpm, err)
+		}
+		packages = append(packages, parseRpmQuery(out)...)
+		break
+	}
+	return packages, nil
+}
+
+// parseDpkgStatus parses the dpkg status file, a deb822 file with a stanza for
+// each package. Packages which are not installed, e.g. removed packages which
+// left their configuration files, are skipped.
+func parseDpkgStatus(data []byte) []Package {
+	var packages []Package
+	for _, stanza := range bytes.Split(data, []byte("\n\n")) {
+		fields := map[string]string{}
+		scanner := bufio.NewScanner(bytes.NewReader(stanza))
+		for scanner.Scan() {
+			line := scanner.Text()
+			// Continuation lines of multiline fields start with a space
+			if line == "" || line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+			if k, v, found := strings.Cut(line, ":"); found {
+				fields[k] = strings.TrimSpace(v)
+			}
+		}
+
+		status := strings.Fields(fields["Status"])
+		if fields["Package"] == "" || len(status) != 3 || status[2] != "installed" {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:      fields["Package"],
+			Installed: true,
+			Version:   fields["Version"],
+			Arch:      fields["Architecture"],
+			Ecosystem: ecosystemDpkg,
+		})
+	}
+	return packages
+}
+
+// parseApkInstalled parses the apk installed database, where each package is
+// a block of "<letter>:<value>" lines.
+func parseApkInstalled(data []byte) []Package {
+	var packages []Package
+	var pkg *Package
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			pkg = nil
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		if pkg == nil {
+			packages = append(packages, Package{Installed: true, Ecosystem: ecosystemApk})
+			pkg = &packages[len(packages)-1]
+		}
+		switch value := line[2:]; line[0] {
+		case 'P':
+			pkg.Name = value
+		case 'V':
+			pkg.Version = value
+		case 'A':
+			pkg.Arch = value
+		}
+	}
+	return packages
+}
+
+// parseRpmQuery parses the output of rpm -qa with rpmQueryFormat.
+func parseRpmQuery(data []byte) []Package {
+	var packages []Package
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] == "gpg-pubkey" {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:      fields[0],
+			Installed: true,
+			Version:   fields[1],
+			Arch:      fields[2],
+			Ecosystem: ecosystemRpm,
+		})
+	}
+	return packages
+}
+
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+type versionConstraints []versionConstraint
+
+// parseVersionConstraints parses comma separated constraints, e.g. ">= 1.2, < 2".
+// A version without an operator must be equal.
+func parseVersionConstraints(s string) (versionConstraints, error) {
+	var constraints versionConstraints
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c := versionConstraint{op: "=", version: part}
+		for _, op := range versionOperator {
+			if strings.HasPrefix(part, op) {
+				c = versionConstraint{op: op, version: strings.TrimSpace(part[len(op):])}
+				break
+			}
+		}
+		if c.version == "" {
+			return nil, fmt.Errorf("invalid version constraint %q", part)
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// match tests if version satisfies all the constraints.
+func (cs versionConstraints) match(ecosystem, version string) bool {
+	for _, c := range cs {
+		cmp := compareVersions(ecosystem, version, c.version)
+		var ok bool
+		switch c.op {
+		case "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
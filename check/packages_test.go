@@ -0,0 +1,225 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDpkgStatus = `Package: openssh-server
+Status: install ok installed
+Priority: optional
+Architecture: amd64
+Version: 1:8.9p1-3ubuntu0.6
+Description: secure shell (SSH) server
+ This is the portable version of OpenSSH.
+
+Package: telnet
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 0.17+2.3-3
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.35-0ubuntu3.6
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.35-0ubuntu3.6
+`
+
+const testApkInstalled = `C:Q1Kz2Xz1k=
+P:musl
+V:1.2.4-r2
+A:x86_64
+S:383152
+T:the musl c library (libc) implementation
+
+C:Q1pU3xZ=
+P:openssl
+V:3.1.4-r5
+A:x86_64
+`
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestPackagesAudit_Execute(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"var/lib/dpkg/status":  testDpkgStatus,
+		"lib/apk/db/installed": testApkInstalled,
+	})
+
+	cases := []struct {
+		name  string
+		audit PackagesAudit
+		want  []Package
+	}{
+		{
+			name:  "all",
+			audit: PackagesAudit{Root: root},
+			want: []Package{
+				{Name: "openssh-server", Installed: true, Version: "1:8.9p1-3ubuntu0.6", Arch: "amd64", Ecosystem: "dpkg"},
+				{Name: "libc6", Installed: true, Version: "2.35-0ubuntu3.6", Arch: "i386", Ecosystem: "dpkg"},
+				{Name: "libc6", Installed: true, Version: "2.35-0ubuntu3.6", Arch: "amd64", Ecosystem: "dpkg"},
+				{Name: "musl", Installed: true, Version: "1.2.4-r2", Arch: "x86_64", Ecosystem: "apk"},
+				{Name: "openssl", Installed: true, Version: "3.1.4-r5", Arch: "x86_64", Ecosystem: "apk"},
+			},
+		},
+		{
+			name:  "names",
+			audit: PackagesAudit{Root: root, Names: []string{"telnet", "openssl"}},
+			want: []Package{
+				{Name: "telnet"},
+				{Name: "openssl", Installed: true, Version: "3.1.4-r5", Arch: "x86_64", Ecosystem: "apk"},
+			},
+		},
+		{
+			name:  "version",
+			audit: PackagesAudit{Root: root, Names: []string{"openssh-server", "openssl", "rsh-client"}, Version: ">= 1:8.9p1-3ubuntu0.5, < 1:9"},
+			want: []Package{
+				{Name: "openssh-server", Installed: true, Version: "1:8.9p1-3ubuntu0.6", Arch: "amd64", Ecosystem: "dpkg", VersionMatches: boolPtr(true)},
+				{Name: "openssl", Installed: true, Version: "3.1.4-r5", Arch: "x86_64", Ecosystem: "apk", VersionMatches: boolPtr(false)},
+				{Name: "rsh-client"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute()
+			assert.Empty(t, errMsg)
+			assert.Empty(t, state)
+			assert.Equal(t, c.want, parseRows[Package](t, out))
+		})
+	}
+}
+
+func TestPackagesAudit_Rpm(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"var/lib/rpm/rpmdb.sqlite": ""})
+	rpm := filepath.Join(t.TempDir(), "rpm")
+	script := "#!/bin/sh\nprintf 'audit\\t3.0.7-103.el9\\tx86_64\\ngpg-pubkey\\t5a6340b3-6229229e\\t(none)\\nbash\\t5.1.8-6.el9_1\\tx86_64\\n'\n"
+	if err := os.WriteFile(rpm, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write %s: %v", rpm, err)
+	}
+
+	audit := PackagesAudit{Root: root, Rpm: rpm, Names: []string{"audit"}, Version: ">= 3.0.7-100"}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Equal(t, []Package{
+		{Name: "audit", Installed: true, Version: "3.0.7-103.el9", Arch: "x86_64", Ecosystem: "rpm", VersionMatches: boolPtr(true)},
+	}, parseRows[Package](t, out))
+
+	audit = PackagesAudit{Root: root, Rpm: filepath.Join(root, "missing")}
+	_, errMsg, state = audit.Execute()
+	assert.NotEmpty(t, errMsg)
+	assert.EqualValues(t, WARN, state)
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		ecosystem string
+		a, b      string
+		want      int
+	}{
+		{ecosystem: "dpkg", a: "1.0", b: "1.0", want: 0},
+		{ecosystem: "dpkg", a: "1.0~rc1", b: "1.0", want: -1},
+		{ecosystem: "dpkg", a: "1.0", b: "1.0+b1", want: -1},
+		{ecosystem: "dpkg", a: "1:1.0", b: "2.0", want: 1},
+		{ecosystem: "dpkg", a: "1.10", b: "1.9", want: 1},
+		{ecosystem: "dpkg", a: "1.0-1", b: "1.0-1ubuntu1", want: -1},
+		{ecosystem: "dpkg", a: "1.0a", b: "1.0+", want: -1},
+		{ecosystem: "dpkg", a: "1.002", b: "1.2", want: 0},
+		{ecosystem: "dpkg", a: "1.a", b: "1.1", want: 1},
+		{ecosystem: "dpkg", a: "1.0a", b: "1.0.1", want: -1},
+		{ecosystem: "dpkg", a: "1.0~", b: "1.0.1", want: -1},
+		{ecosystem: "rpm", a: "1.0", b: "1.0", want: 0},
+		{ecosystem: "rpm", a: "1.0~rc1", b: "1.0", want: -1},
+		{ecosystem: "rpm", a: "1.0^git1", b: "1.0", want: 1},
+		{ecosystem: "rpm", a: "1.0^git1", b: "1.0.1", want: -1},
+		{ecosystem: "rpm", a: "1.0a", b: "1.0", want: 1},
+		{ecosystem: "rpm", a: "1.0", b: "1.a", want: 1},
+		{ecosystem: "rpm", a: "2.0-1.el9", b: "2.0", want: 0},
+		{ecosystem: "rpm", a: "1:1.0-1", b: "2.0-1", want: 1},
+		{ecosystem: "rpm", a: "5.1.8-6.el9_1", b: "5.1.8-6.el9", want: 1},
+		{ecosystem: "apk", a: "1.2.4-r2", b: "1.2.4-r10", want: -1},
+		{ecosystem: "apk", a: "1.2.4_rc1", b: "1.2.4", want: -1},
+		{ecosystem: "apk", a: "1.2.4_p1", b: "1.2.4", want: 1},
+		{ecosystem: "apk", a: "1.2.4a", b: "1.2.4", want: 1},
+		{ecosystem: "apk", a: "1.2.10", b: "1.2.9", want: 1},
+		{ecosystem: "apk", a: "1.2", b: "1.2.0", want: -1},
+		{ecosystem: "apk", a: "3.1.4-r5", b: "3.1.4-r5", want: 0},
+		{ecosystem: "semver", a: "1.24", b: "1.24.0", want: 0},
+		{ecosystem: "semver", a: "1.24.0-rc.1", b: "1.24.0", want: -1},
+		{ecosystem: "semver", a: "1.24.0-rc.2", b: "1.24.0-rc.10", want: -1},
+		{ecosystem: "semver", a: "1.24.0-alpha", b: "1.24.0-alpha.1", want: -1},
+		{ecosystem: "semver", a: "5.14.0-284.11.1.el9_2.x86_64", b: "5.14", want: 1},
+		{ecosystem: "semver", a: "5.14.0-70.13.1.el9_0", b: "5.14.0-284.11.1.el9_2", want: -1},
+		{ecosystem: "semver", a: "5.15.0-rc1", b: "5.14.0-284", want: 1},
+		{ecosystem: "semver", a: "1.26.1+k3s1", b: "1.26.1", want: 0},
+		{ecosystem: "semver", a: "1.10", b: "1.9.9", want: 1},
+		{ecosystem: "semver", a: "22.04", b: "20.04", want: 1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, compareVersions(c.ecosystem, c.a, c.b), "%s: %s and %s", c.ecosystem, c.a, c.b)
+		assert.Equal(t, -c.want, compareVersions(c.ecosystem, c.b, c.a), "%s: %s and %s", c.ecosystem, c.b, c.a)
+	}
+}
+
+func TestPackagesAudit_Check(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"var/lib/dpkg/status": testDpkgStatus})
+
+	controls := `---
+controls:
+id: 2
+text: "Services"
+groups:
+- id: 2.2
+  text: "Client Services"
+  checks:
+    - id: 2.2.4
+      text: "Ensure telnet client is not installed"
+      audittype: "packages"
+      audit:
+        root: "` + root + `"
+        names: ["telnet"]
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.installed}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.2.1
+      text: "Ensure a patched OpenSSH server is installed"
+      audittype: "packages"
+      audit:
+        root: "` + root + `"
+        names: ["openssh-server"]
+        version: ">= 1:9.6p1"
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.version_matches}"
+          compare:
+            op: eq
+            value: true
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1, Fail: 1}, summary)
+}
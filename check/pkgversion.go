@@ -0,0 +1,382 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two versions following the rules of the package
+// ecosystem. It returns a negative number when a is older than b, a positive
+// number when a is newer, and 0 when they are equal.
+func compareVersions(ecosystem, a, b string) int {
+	switch ecosystem {
+	case ecosystemApk:
+		return compareApkVersions(a, b)
+	case ecosystemRpm:
+		return compareRpmVersions(a, b)
+	case versionSemver:
+		return compareSemver(a, b)
+	}
+	return compareDpkgVersions(a, b)
+}
+
+// versionSemver orders versions which are not those of a package, such as the
+// versions of constraints, like semantic versions.
+const versionSemver = "semver"
+
+// compareSemver compares versions by the precedence of semantic versioning.
+// Missing components of the version are 0, so 1.24 is 1.24.0, and versions
+// with a pre-release, e.g. 1.24.0-rc.1, are older than the release. Build
+// metadata is ignored. A suffix starting with a digit is rather the revision
+// of a distribution, e.g. 5.14.0-284.11.1.el9_2 for kernels, newer than the
+// version without it.
+func compareSemver(a, b string) int {
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aSuffix, _ := strings.Cut(a, "-")
+	bCore, bSuffix, _ := strings.Cut(b, "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if cmp := compareSemverIdentifiers(aPart, bPart); cmp != 0 {
+			return cmp
+		}
+	}
+
+	if cmp := sign(semverSuffixRank(aSuffix) - semverSuffixRank(bSuffix)); cmp != 0 || aSuffix == "" {
+		return cmp
+	}
+	aIDs, bIDs := strings.Split(aSuffix, "."), strings.Split(bSuffix, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if cmp := compareSemverIdentifiers(aIDs[i], bIDs[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return sign(len(aIDs) - len(bIDs))
+}
+
+// semverSuffixRank orders the versions with a pre-release before the release,
+// and those with a revision after it.
+func semverSuffixRank(suffix string) int {
+	switch {
+	case suffix == "":
+		return 0
+	case isDigit(suffix[0]):
+		return 1
+	}
+	return -1
+}
+
+// compareSemverIdentifiers compares numeric identifiers numerically, and
+// others in ASCII order. Numeric identifiers are older than the others.
+func compareSemverIdentifiers(a, b string) int {
+	aNum, aErr := strconv.ParseUint(a, 10, 64)
+	bNum, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// splitEpoch splits [epoch:]version into its epoch, 0 by default, and version.
+func splitEpoch(v string) (int, string) {
+	if e, rest, found := strings.Cut(v, ":"); found {
+		if epoch, err := strconv.Atoi(e); err == nil {
+			return epoch, rest
+		}
+	}
+	return 0, v
+}
+
+// splitRevision splits version[-revision] at the last hyphen.
+func splitRevision(v string) (string, string) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// compareDpkgVersions compares [epoch:]upstream[-revision] versions as
+// deb-version(7) describes.
+func compareDpkgVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+	upstreamA, revisionA := splitRevision(a)
+	upstreamB, revisionB := splitRevision(b)
+	if c := dpkgVerrevcmp(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return dpkgVerrevcmp(revisionA, revisionB)
+}
+
+// dpkgOrder orders the non digit characters of a version: "~" sorts before
+// anything, even the end of the version, and letters sort before the other
+// characters. A digit ends the non digit part, so it sorts like the end.
+func dpkgOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case s[0] == '~':
+		return -1
+	case isLetter(s[0]):
+		return int(s[0])
+	}
+	return int(s[0]) + 256
+}
+
+func dpkgVerrevcmp(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			if oa, ob := dpkgOrder(a), dpkgOrder(b); oa != ob {
+				return sign(oa - ob)
+			}
+			a, b = a[min(1, len(a)):], b[min(1, len(b)):]
+		}
+
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		firstDiff := 0
+		for a != "" && isDigit(a[0]) && b != "" && isDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// compareRpmVersions compares [epoch:]version[-release] versions like rpm.
+// The release is only compared when both versions have one.
+func compareRpmVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+	versionA, releaseA := splitRevision(a)
+	versionB, releaseB := splitRevision(b)
+	if c := rpmvercmp(versionA, versionB); c != 0 || releaseA == "" || releaseB == "" {
+		return c
+	}
+	return rpmvercmp(releaseA, releaseB)
+}
+
+// rpmvercmp compares the alphanumeric segments of two versions. Numeric
+// segments are newer than alphabetic ones, "~" sorts before anything and "^"
+// sorts after the end of a version but before anything else.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	isSep := func(c byte) bool { return !isDigit(c) && !isLetter(c) && c != '~' && c != '^' }
+
+	for a != "" || b != "" {
+		for a != "" && isSep(a[0]) {
+			a = a[1:]
+		}
+		for b != "" && isSep(b[0]) {
+			b = b[1:]
+		}
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case a[0] != '^':
+				return 1
+			case b[0] != '^':
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		segment := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && ((isNum && isDigit(s[i])) || (!isNum && isLetter(s[i]))) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+		var segA, segB string
+		segA, a = segment(a)
+		segB, b = segment(b)
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a != "":
+		return 1
+	}
+	return -1
+}
+
+// apkSuffixes orders the suffixes of apk versions. Pre-release suffixes sort
+// before a version without suffix, and the others after it.
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+type apkVersion struct {
+	numbers  []string
+	letter   byte
+	suffixes [][2]int
+	revision int
+}
+
+func parseApkVersion(v string) apkVersion {
+	var version apkVersion
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		if rev, err := strconv.Atoi(v[i+2:]); err == nil {
+			version.revision = rev
+			v = v[:i]
+		}
+	}
+	parts := strings.Split(v, "_")
+	for _, suffix := range parts[1:] {
+		name := strings.TrimRight(suffix, "0123456789")
+		n, _ := strconv.Atoi(suffix[len(name):])
+		version.suffixes = append(version.suffixes, [2]int{apkSuffixes[name], n})
+	}
+	v = parts[0]
+	if v != "" && isLetter(v[len(v)-1]) {
+		version.letter = v[len(v)-1]
+		v = v[:len(v)-1]
+	}
+	version.numbers = strings.Split(v, ".")
+	return version
+}
+
+// compareApkVersions compares versions like apk: dotted numbers, an optional
+// letter, suffixes such as _rc1 or _p2, and a -r revision.
+func compareApkVersions(a, b string) int {
+	va, vb := parseApkVersion(a), parseApkVersion(b)
+
+	for i := 0; i < len(va.numbers) && i < len(vb.numbers); i++ {
+		na, nb := strings.TrimLeft(va.numbers[i], "0"), strings.TrimLeft(vb.numbers[i], "0")
+		if len(na) != len(nb) {
+			return sign(len(na) - len(nb))
+		}
+		if c := strings.Compare(na, nb); c != 0 {
+			return c
+		}
+	}
+	if len(va.numbers) != len(vb.numbers) {
+		return sign(len(va.numbers) - len(vb.numbers))
+	}
+	if va.letter != vb.letter {
+		return sign(int(va.letter) - int(vb.letter))
+	}
+	for i := 0; i < len(va.suffixes) || i < len(vb.suffixes); i++ {
+		var sa, sb [2]int
+		if i < len(va.suffixes) {
+			sa = va.suffixes[i]
+		}
+		if i < len(vb.suffixes) {
+			sb = vb.suffixes[i]
+		}
+		if sa[0] != sb[0] {
+			return sign(sa[0] - sb[0])
+		}
+		if sa[1] != sb[1] {
+			return sign(sa[1] - sb[1])
+		}
+	}
+	return sign(va.revision - vb.revision)
+}
@@ -0,0 +1,71 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aquasecurity/bench-common/auditeval"
+)
+
+// AuditResult is the result of an audit which runs a command, with its
+// streams, exit code and duration reported separately.
+type AuditResult struct {
+	// Output is what tests without a source evaluate.
+	Output   string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+	// CommandNotFound is set when the command to run does not exist.
+	CommandNotFound bool
+	// sourced is set by audits which report the fields above, so tests
+	// can use them as a source.
+	sourced bool
+}
+
+// ResultAuditer is implemented by audit types which run a command, so tests
+// can evaluate its stdout, stderr, exit code or duration.
+type ResultAuditer interface {
+	Auditer
+	ExecuteResult(customConfig ...interface{}) (result AuditResult, errMessage string, state State)
+}
+
+// sources returns the values tests can select with source, or nil when the
+// audit only returned an output.
+func (r AuditResult) sources() map[string]string {
+	if !r.sourced {
+		return nil
+	}
+	return map[string]string{
+		auditeval.SourceStdout:   r.Stdout,
+		auditeval.SourceStderr:   r.Stderr,
+		auditeval.SourceExitCode: strconv.Itoa(r.ExitCode),
+		auditeval.SourceDuration: strconv.FormatInt(r.Duration.Milliseconds(), 10),
+	}
+}
+
+// executeAudit runs the audit, with its result reported separately when the
+// audit type supports it.
+func executeAudit(auditer Auditer, customConfig ...interface{}) (result AuditResult, errMessage string, state State) {
+	if r, ok := auditer.(ResultAuditer); ok {
+		result, errMessage, state = r.ExecuteResult(customConfig...)
+		result.sourced = true
+		return result, errMessage, state
+	}
+	result.Output, errMessage, state = auditer.Execute(customConfig...)
+	return result, errMessage, state
+}
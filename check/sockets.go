@@ -0,0 +1,304 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeSockets audits listening sockets.
+const TypeSockets = "sockets"
+
+const (
+	tcpListen       = "0A"
+	udpUnconnected  = "07"
+	unixAcceptConn  = 0x10000
+	socketLinkStart = "socket:["
+)
+
+var socketProtocols = []string{"tcp", "tcp6", "udp", "udp6", "unix"}
+
+// SocketsAudit reads the listening sockets from /proc/net and maps them to the
+// processes owning them through /proc/<pid>/fd.
+type SocketsAudit struct {
+	Protocols []string `yaml:"protocols"`
+	Ports     []int    `yaml:"ports"`
+	ProcRoot  string   `yaml:"proc_root"`
+}
+
+// Listener is a listening socket. Unix sockets have a path instead of an
+// address and port.
+type Listener struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+	UID      *int   `json:"uid,omitempty"`
+	Inode    uint64 `json:"inode"`
+	PID      int    `json:"pid,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Exe      string `json:"exe,omitempty"`
+}
+
+type socketOwner struct {
+	pid     int
+	command string
+	exe     string
+}
+
+// Execute returns a row for each listening socket.
+func (s *SocketsAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	target := targetFrom(customConfig)
+	procRoot := target.path(rootOrDefault(s.ProcRoot, "/proc"))
+	fsys := target.fs()
+	protocols := s.Protocols
+	if len(protocols) == 0 {
+		protocols = socketProtocols
+	}
+
+	order, err := procNetByteOrder(target)
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	var listeners []Listener
+	for _, protocol := range protocols {
+		if !contains(socketProtocols, protocol) {
+			return auditFailed(fmt.Errorf("unknown socket protocol %q", protocol))
+		}
+
+		path := filepath.Join(procRoot, "net", protocol)
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			// IPv6 may be disabled, in which case its tables don't exist
+			if os.IsNotExist(err) {
+				continue
+			}
+			return auditFailed(fmt.Errorf("failed to read %s: %v", path, err))
+		}
+
+		if protocol == "unix" {
+			listeners = append(listeners, parseUnixSockets(data)...)
+		} else {
+			listeners = append(listeners, parseInetSockets(protocol, data, order)...)
+		}
+	}
+
+	if len(s.Ports) > 0 {
+		filtered := listeners[:0]
+		for _, l := range listeners {
+			for _, port := range s.Ports {
+				if l.Port == port {
+					filtered = append(filtered, l)
+					break
+				}
+			}
+		}
+		listeners = filtered
+	}
+
+	owners := socketOwners(fsys, procRoot)
+	for i := range listeners {
+		if owner, ok := owners[listeners[i].Inode]; ok {
+			listeners[i].PID = owner.pid
+			listeners[i].Command = owner.command
+			listeners[i].Exe = owner.exe
+		}
+	}
+
+	return jsonLinesResult(listeners)
+}
+
+// procByteOrder is the byte order of the addresses in /proc/net of the host
+// the bench runs on, which the kernel writes in the order of the host.
+var procByteOrder binary.ByteOrder = binary.NativeEndian
+
+// procNetByteOrder returns the byte order of the addresses in /proc/net of
+// the target. Remote hosts may have another architecture than the host the
+// bench runs on.
+func procNetByteOrder(target *Target) (binary.ByteOrder, error) {
+	if target.ssh == nil {
+		return procByteOrder, nil
+	}
+	out, err := target.output("uname", "-m")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the architecture of the target: %v", err)
+	}
+	return machineByteOrder(strings.TrimSpace(string(out)))
+}
+
+// machineByteOrder returns the byte order of machine, as printed by uname -m.
+// Machines whose name doesn't tell their byte order, such as mips, are refused.
+func machineByteOrder(machine string) (binary.ByteOrder, error) {
+	switch machine {
+	case "x86_64", "amd64", "i386", "i486", "i586", "i686", "aarch64", "arm64", "ppc64le", "riscv32", "riscv64", "loongarch64":
+		return binary.LittleEndian, nil
+	case "s390", "s390x", "ppc", "ppc64", "sparc", "sparc64", "aarch64_be":
+		return binary.BigEndian, nil
+	}
+	// e.g. armv7l and armv5teb
+	if strings.HasPrefix(machine, "arm") && strings.HasSuffix(machine, "l") {
+		return binary.LittleEndian, nil
+	}
+	if strings.HasPrefix(machine, "arm") && strings.HasSuffix(machine, "b") {
+		return binary.BigEndian, nil
+	}
+	return nil, fmt.Errorf("unknown byte order of the %q architecture of the target", machine)
+}
+
+// parseInetSockets parses the listening sockets of /proc/net/{tcp,tcp6,udp,udp6}.
+// Unconnected UDP sockets are considered to be listening.
+func parseInetSockets(protocol string, data []byte, order binary.ByteOrder) []Listener {
+	listenState := tcpListen
+	if strings.HasPrefix(protocol, "udp") {
+		listenState = udpUnconnected
+	}
+
+	var listeners []Listener
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != listenState {
+			continue
+		}
+
+		address, port, err := parseSocketAddress(fields[1], order)
+		if err != nil {
+			continue
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		l := Listener{Protocol: protocol, Address: address, Port: port, Inode: inode}
+		if uid, err := strconv.Atoi(fields[7]); err == nil {
+			l.UID = &uid
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// parseSocketAddress decodes an address in the /proc/net format, e.g.
+// "0100007F:1F90" for 127.0.0.1:8080 on a little endian host. Addresses are
+// stored as 32 bit words in the byte order of the host.
+func parseSocketAddress(s string, order binary.ByteOrder) (string, int, error) {
+	hexIP, hexPort, found := strings.Cut(s, ":")
+	if !found {
+		return "", 0, fmt.Errorf("invalid socket address %q", s)
+	}
+
+	b, err := hex.DecodeString(hexIP)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid socket address %q", s)
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], order.Uint32(b[i:]))
+	}
+
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid socket port %q", s)
+	}
+	return ip.String(), int(port), nil
+}
+
+// parseUnixSockets parses the listening sockets of /proc/net/unix.
+func parseUnixSockets(data []byte) []Listener {
+	var listeners []Listener
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&unixAcceptConn == 0 {
+			continue
+		}
+		inode, _ := strconv.ParseUint(fields[6], 10, 64)
+
+		l := Listener{Protocol: "unix", Inode: inode}
+		if len(fields) > 7 {
+			l.Path = fields[7]
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// socketOwners maps socket inodes to the process with the lowest pid holding
+// them open. Processes which can't be inspected are ignored.
+func socketOwners(fsys targetFS, procRoot string) map[uint64]socketOwner {
+	owners := map[uint64]socketOwner{}
+
+	entries, err := fsys.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	for _, pid := range pids {
+		pidDir := filepath.Join(procRoot, strconv.Itoa(pid))
+		fds, err := fsys.ReadDir(filepath.Join(pidDir, "fd"))
+		if err != nil {
+			continue
+		}
+
+		var owner *socketOwner
+		for _, fd := range fds {
+			link, err := fsys.Readlink(filepath.Join(pidDir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, socketLinkStart) {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, socketLinkStart), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; ok {
+				continue
+			}
+
+			if owner == nil {
+				owner = &socketOwner{pid: pid}
+				if comm, err := fsys.ReadFile(filepath.Join(pidDir, "comm")); err == nil {
+					owner.command = strings.TrimSpace(string(comm))
+				}
+				owner.exe, _ = fsys.Readlink(filepath.Join(pidDir, "exe"))
+			}
+			owners[inode] = *owner
+		}
+	}
+	return owners
+}
@@ -0,0 +1,244 @@
+package check
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testProcNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:2837 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 00000000:27FF 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:2837 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+`
+
+const testProcNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+`
+
+const testProcNetUDP = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 3500007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 1005 2 0000000000000000 0
+`
+
+const testProcNetUnix = `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 1006 /run/docker.sock
+0000000000000000: 00000003 00000000 00000000 0001 03 1007 /run/docker.sock
+0000000000000000: 00000002 00000000 00010000 0001 01 1008
+`
+
+func writeTestProcNet(t *testing.T) string {
+	t.Helper()
+	// The tables were taken from a little endian host
+	order := procByteOrder
+	procByteOrder = binary.LittleEndian
+	t.Cleanup(func() { procByteOrder = order })
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"net/tcp":      testProcNetTCP,
+		"net/tcp6":     testProcNetTCP6,
+		"net/udp":      testProcNetUDP,
+		"net/unix":     testProcNetUnix,
+		"1/comm":       "systemd\n",
+		"42/comm":      "dockerd\n",
+		"42/fd/.keep":  "",
+		"100/comm":     "kubelet\n",
+		"100/fd/.keep": "",
+	})
+
+	links := map[string]string{
+		"42/fd/3":  "socket:[1006]",
+		"42/fd/4":  "/dev/null",
+		"42/exe":   "/usr/bin/dockerd",
+		"100/fd/5": "socket:[1002]",
+		"100/fd/6": "socket:[1001]",
+		"100/exe":  "/usr/bin/kubelet",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatalf("failed to link %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestSocketsAudit_Execute(t *testing.T) {
+	procRoot := writeTestProcNet(t)
+
+	out, errMsg, state := (&SocketsAudit{ProcRoot: procRoot}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	uid0, uid101 := 0, 101
+	expected := []Listener{
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 10295, UID: &uid0, Inode: 1001, PID: 100, Command: "kubelet", Exe: "/usr/bin/kubelet"},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 10239, UID: &uid0, Inode: 1002, PID: 100, Command: "kubelet", Exe: "/usr/bin/kubelet"},
+		{Protocol: "tcp6", Address: "::1", Port: 22, UID: &uid0, Inode: 1004},
+		{Protocol: "udp", Address: "127.0.0.53", Port: 53, UID: &uid101, Inode: 1005},
+		{Protocol: "unix", Path: "/run/docker.sock", Inode: 1006, PID: 42, Command: "dockerd", Exe: "/usr/bin/dockerd"},
+		{Protocol: "unix", Inode: 1008},
+	}
+	assert.Equal(t, expected, parseRows[Listener](t, out))
+}
+
+func TestSocketsAudit_Filters(t *testing.T) {
+	procRoot := writeTestProcNet(t)
+
+	out, errMsg, state := (&SocketsAudit{ProcRoot: procRoot, Protocols: []string{"tcp", "udp"}, Ports: []int{10239, 53}}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	var ports []int
+	for _, l := range parseRows[Listener](t, out) {
+		ports = append(ports, l.Port)
+	}
+	assert.Equal(t, []int{10239, 53}, ports)
+
+	_, errMsg, state = (&SocketsAudit{ProcRoot: procRoot, Protocols: []string{"sctp"}}).Execute()
+	if state != WARN || errMsg == "" {
+		t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+	}
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	cases := []struct {
+		in      string
+		order   binary.ByteOrder
+		address string
+		port    int
+		wantErr bool
+	}{
+		{in: "0100007F:1F90", address: "127.0.0.1", port: 8080},
+		{in: "7F000001:1F90", order: binary.BigEndian, address: "127.0.0.1", port: 8080},
+		{in: "20010DB8000000000000000000000001:01BB", order: binary.BigEndian, address: "2001:db8::1", port: 443},
+		{in: "00000000:0016", address: "0.0.0.0", port: 22},
+		{in: "00000000000000000000000000000000:0050", address: "::", port: 80},
+		{in: "0000000000000000FFFF00000100007F:0050", address: "127.0.0.1", port: 80},
+		{in: "B80D0120000000000000000001000000:01BB", address: "2001:db8::1", port: 443},
+		{in: "0100007F", wantErr: true},
+		{in: "zz:0050", wantErr: true},
+	}
+
+	for _, c := range cases {
+		order := c.order
+		if order == nil {
+			order = binary.LittleEndian
+		}
+		address, port, err := parseSocketAddress(c.in, order)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.in, err)
+			continue
+		}
+		if address != c.address || port != c.port {
+			t.Errorf("%s: expected %s:%d, got %s:%d", c.in, c.address, c.port, address, port)
+		}
+	}
+}
+
+func TestSocketsAudit_Check(t *testing.T) {
+	procRoot := writeTestProcNet(t)
+
+	controls := `---
+controls:
+id: 4
+text: "Worker Node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+    - id: 4.2.1
+      text: "Ensure the kubelet only listens on localhost"
+      audittype: "sockets"
+      audit:
+        protocols: ["tcp", "tcp6"]
+        proc_root: "` + procRoot + `"
+      use_multiple_values: true
+      tests:
+        bin_op: or
+        test_items:
+        - path: "{.address}"
+          compare:
+            op: cidr
+            value: "127.0.0.0/8,::1/128"
+        - path: "{.command}"
+          compare:
+            op: noteq
+            value: kubelet
+      scored: true
+    - id: 4.2.2
+      text: "Ensure docker is not listening on tcp 2375"
+      audittype: "sockets"
+      audit:
+        protocols: ["tcp", "tcp6"]
+        proc_root: "` + procRoot + `"
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.port}"
+          compare:
+            op: noteq
+            value: 2375
+      scored: true
+    - id: 4.2.3
+      text: "Ensure the kubelet read-only port only listens on localhost"
+      audittype: "sockets"
+      audit:
+        protocols: ["tcp", "tcp6"]
+        ports: [10255]
+        proc_root: "` + procRoot + `"
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.address}"
+          compare:
+            op: cidr
+            value: "127.0.0.0/8,::1/128"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2, Fail: 1}, summary)
+	// Nothing listens on the read-only port
+	assert.Equal(t, "", c.Groups[0].Checks[2].ActualValue)
+}
+
+func TestMachineByteOrder(t *testing.T) {
+	cases := []struct {
+		machine string
+		order   binary.ByteOrder
+	}{
+		{machine: "x86_64", order: binary.LittleEndian},
+		{machine: "aarch64", order: binary.LittleEndian},
+		{machine: "armv7l", order: binary.LittleEndian},
+		{machine: "ppc64le", order: binary.LittleEndian},
+		{machine: "s390x", order: binary.BigEndian},
+		{machine: "ppc64", order: binary.BigEndian},
+		{machine: "armv5teb", order: binary.BigEndian},
+		// mips hosts print mips whatever their byte order
+		{machine: "mips"},
+		{machine: ""},
+	}
+	for _, c := range cases {
+		order, err := machineByteOrder(c.machine)
+		if c.order == nil {
+			assert.Error(t, err, c.machine)
+			continue
+		}
+		assert.NoError(t, err, c.machine)
+		assert.Equal(t, c.order, order, c.machine)
+	}
+}
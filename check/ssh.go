@@ -0,0 +1,372 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultSSHPort        = "22"
+	defaultSSHMaxSessions = 4
+	defaultSSHTimeout     = 30 * time.Second
+)
+
+// SSHConfig configures the connection to the remote host of an SSH target.
+type SSHConfig struct {
+	// Address is the host of the SSH server, with port 22 by default.
+	Address string
+	User    string
+	// KeyFiles are the private keys to authenticate with.
+	KeyFiles []string
+	// Agent authenticates with the keys of the SSH agent listening at
+	// SSH_AUTH_SOCK.
+	Agent bool
+	// KnownHosts are the known_hosts files verifying the key of the host,
+	// ~/.ssh/known_hosts by default.
+	KnownHosts []string
+	// MaxSessions limits the commands run at once on the host, as sshd
+	// refuses sessions above its MaxSessions setting. 4 by default.
+	MaxSessions int
+	// Timeout limits the time to connect, 30s by default.
+	Timeout time.Duration
+}
+
+// DialSSH connects to a remote host, returning a target which runs the
+// commands of audits on the host and reads the files of native audit types
+// over SFTP. Close the target to disconnect.
+func DialSSH(config SSHConfig) (*Target, error) {
+	address := config.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultSSHPort)
+	}
+
+	c := &sshClient{host: config.Address, user: config.User}
+	auth, err := c.authMethods(config)
+	if err != nil {
+		c.Close() // nolint: errcheck
+		return nil, err
+	}
+	hostKeyCallback, err := c.hostKeyCallback(config.KnownHosts)
+	if err != nil {
+		c.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultSSHTimeout
+	}
+	c.client, err = ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            config.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		c.Close() // nolint: errcheck
+		return nil, fmt.Errorf("failed to connect to %s: %v", config.Address, err)
+	}
+	c.sftp, err = sftp.NewClient(c.client)
+	if err != nil {
+		c.Close() // nolint: errcheck
+		return nil, fmt.Errorf("failed to start SFTP on %s: %v", config.Address, err)
+	}
+
+	maxSessions := config.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultSSHMaxSessions
+	}
+	c.sessions = make(chan struct{}, maxSessions)
+	return &Target{Type: TargetSSH, ssh: c}, nil
+}
+
+// sshClient is the connection to the remote host of an SSH target.
+type sshClient struct {
+	client *ssh.Client
+	sftp   *sftp.Client
+	agent  net.Conn
+	// sessions holds a token for each command running on the host
+	sessions chan struct{}
+
+	host    string
+	user    string
+	hostKey string
+}
+
+func (c *sshClient) authMethods(config SSHConfig) ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+	for _, keyFile := range config.KeyFiles {
+		pem, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			return nil, fmt.Errorf("SSH key %s is protected by a passphrase, add it to the SSH agent instead", keyFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH key %s: %v", keyFile, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	auth := []ssh.AuthMethod{}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if config.Agent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, fmt.Errorf("SSH agent requested, but SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH agent: %v", err)
+		}
+		c.agent = conn
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no SSH key or agent to authenticate with")
+	}
+	return auth, nil
+}
+
+// hostKeyCallback verifies the key of the host with the known_hosts files,
+// and records its fingerprint for the report.
+func (c *sshClient) hostKeyCallback(files []string) (ssh.HostKeyCallback, error) {
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known_hosts: %v", err)
+		}
+		files = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+	verify, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %v", err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		var keyErr *knownhosts.KeyError
+		err := verify(hostname, remote, key)
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return fmt.Errorf("host key %s of %s is not in known_hosts", ssh.FingerprintSHA256(key), hostname)
+		}
+		if err != nil {
+			return err
+		}
+		c.hostKey = ssh.FingerprintSHA256(key)
+		return nil
+	}, nil
+}
+
+func (c *sshClient) fs() targetFS {
+	return sftpFS{client: c.sftp}
+}
+
+// run runs args on the host. The host runs the command with the login shell
+// of the user, so the arguments are quoted.
+func (c *sshClient) run(ctx context.Context, args []string, cmd targetCommand) error {
+	select {
+	case c.sessions <- struct{}{}:
+		defer func() { <-c.sessions }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %v", err)
+	}
+	defer session.Close()
+	session.Stdin, session.Stdout, session.Stderr = cmd.stdin, cmd.stdout, cmd.stderr
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Signal(ssh.SIGKILL) // nolint: errcheck
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	err = session.Run(remoteCommand(args, cmd))
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitError{code: exitErr.ExitStatus(), err: fmt.Errorf("exit status %d", exitErr.ExitStatus())}
+	}
+	return err
+}
+
+// dial connects to address from the host, through the SSH connection.
+func (c *sshClient) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	type dialed struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan dialed, 1)
+	go func() {
+		conn, err := c.client.Dial(network, address)
+		ch <- dialed{conn, err}
+	}()
+	select {
+	case d := <-ch:
+		return d.conn, d.err
+	case <-ctx.Done():
+		go func() {
+			if d := <-ch; d.conn != nil {
+				d.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Close disconnects from the host.
+func (c *sshClient) Close() error {
+	var err error
+	if c.sftp != nil {
+		c.sftp.Close() // nolint: errcheck
+	}
+	if c.client != nil {
+		err = c.client.Close()
+	}
+	if c.agent != nil {
+		c.agent.Close()
+	}
+	return err
+}
+
+// remoteCommand returns the command line running args in dir, with env.
+func remoteCommand(args []string, cmd targetCommand) string {
+	var words []string
+	if cmd.dir != "" {
+		words = append(words, "cd", shellQuote(cmd.dir), "&&")
+	}
+	if cmd.clearEnv || len(cmd.env) > 0 {
+		words = append(words, "env")
+		if cmd.clearEnv {
+			words = append(words, "-i")
+		}
+		for _, kv := range cmd.env {
+			words = append(words, shellQuote(kv))
+		}
+	}
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sftpFS is the filesystem of the host of an SSH target.
+type sftpFS struct {
+	client *sftp.Client
+}
+
+func (f sftpFS) ReadFile(name string) ([]byte, error) {
+	file, err := f.client.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer file.Close()
+	// Files of /proc and /sys report a zero size, read until EOF
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (f sftpFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := f.client.ReadDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, fs.FileInfoToDirEntry(remoteFileInfo{info}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (f sftpFS) Lstat(name string) (fs.FileInfo, error) {
+	info, err := f.client.Lstat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return remoteFileInfo{info}, nil
+}
+
+func (f sftpFS) Readlink(name string) (string, error) {
+	link, err := f.client.ReadLink(name)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return link, nil
+}
+
+func (f sftpFS) Glob(pattern string) ([]string, error) {
+	return f.client.Glob(pattern)
+}
+
+func (f sftpFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return walkDir(f, root, fn)
+}
+
+// remoteFileInfo reports the owner of a remote file as a *remoteFileStat.
+type remoteFileInfo struct {
+	fs.FileInfo
+}
+
+// remoteFileStat is the owner of a remote file. SFTP does not report the
+// device of files.
+type remoteFileStat struct {
+	UID, GID uint32
+}
+
+func (i remoteFileInfo) Sys() interface{} {
+	if st, ok := i.FileInfo.Sys().(*sftp.FileStat); ok {
+		return &remoteFileStat{UID: st.UID, GID: st.GID}
+	}
+	return nil
+}
@@ -0,0 +1,491 @@
+package check
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an SSH server running commands with the local shell and
+// serving the local filesystem over SFTP, like a remote host would.
+type testSSHServer struct {
+	addr       string
+	hostKey    ssh.Signer
+	clientKey  ed25519.PrivateKey
+	keyFile    string
+	knownHosts string
+
+	active    int32
+	maxActive int32
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	dir := t.TempDir()
+	s := &testSSHServer{}
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+	if s.hostKey, err = ssh.NewSignerFromKey(hostPriv); err != nil {
+		t.Fatalf("failed to create host key signer: %v", err)
+	}
+	_, s.clientKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(s.clientKey)
+	if err != nil {
+		t.Fatalf("failed to marshal client key: %v", err)
+	}
+	s.keyFile = filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(s.keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write client key: %v", err)
+	}
+	clientPub, err := ssh.NewPublicKey(s.clientKey.Public())
+	if err != nil {
+		t.Fatalf("failed to create client public key: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientPub.Marshal()) {
+				return nil, fmt.Errorf("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(s.hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s.addr = listener.Addr().String()
+
+	s.knownHosts = filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, s.hostKey.PublicKey())
+	if err := os.WriteFile(s.knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.session(newChannel)
+		case "direct-tcpip":
+			var payload struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			ssh.Unmarshal(newChannel.ExtraData(), &payload) // nolint: errcheck
+			go forward(newChannel, "tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		case "direct-streamlocal@openssh.com":
+			var payload struct {
+				SocketPath string
+				Reserved0  string
+				Reserved1  uint32
+			}
+			ssh.Unmarshal(newChannel.ExtraData(), &payload) // nolint: errcheck
+			go forward(newChannel, "unix", payload.SocketPath)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel") // nolint: errcheck
+		}
+	}
+}
+
+func (s *testSSHServer) session(newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	for req := range reqs {
+		var payload struct{ Value string }
+		ssh.Unmarshal(req.Payload, &payload) // nolint: errcheck
+		switch {
+		case req.Type == "exec":
+			req.Reply(true, nil) // nolint: errcheck
+			go s.exec(channel, payload.Value)
+		case req.Type == "subsystem" && payload.Value == "sftp":
+			req.Reply(true, nil) // nolint: errcheck
+			go func() {
+				defer channel.Close()
+				if server, err := sftp.NewServer(channel); err == nil {
+					server.Serve() // nolint: errcheck
+				}
+			}()
+		default:
+			req.Reply(false, nil) // nolint: errcheck
+		}
+	}
+}
+
+func (s *testSSHServer) exec(channel ssh.Channel, command string) {
+	defer channel.Close()
+	active := atomic.AddInt32(&s.active, 1)
+	for {
+		max := atomic.LoadInt32(&s.maxActive)
+		if active <= max || atomic.CompareAndSwapInt32(&s.maxActive, max, active) {
+			break
+		}
+	}
+	defer atomic.AddInt32(&s.active, -1)
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), "BENCH_SSH_SERVER=remote")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = channel, channel, channel.Stderr()
+	status := 0
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		status = exitErr.ExitCode()
+	} else if err != nil {
+		status = 255
+	}
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)})) // nolint: errcheck
+}
+
+func forward(newChannel ssh.NewChannel, network, address string) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error()) // nolint: errcheck
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		io.Copy(conn, channel) // nolint: errcheck
+		conn.Close()
+	}()
+	io.Copy(channel, conn) // nolint: errcheck
+	channel.Close()
+}
+
+// dial connects to the server with the client key.
+func (s *testSSHServer) dial(t *testing.T, config SSHConfig) *Target {
+	t.Helper()
+	config.Address = s.addr
+	config.User = "bench"
+	if len(config.KeyFiles) == 0 && !config.Agent {
+		config.KeyFiles = []string{s.keyFile}
+	}
+	config.KnownHosts = []string{s.knownHosts}
+	target, err := DialSSH(config)
+	if err != nil {
+		t.Fatalf("failed to connect to the test SSH server: %v", err)
+	}
+	t.Cleanup(func() { target.Close() })
+	return target
+}
+
+func TestDialSSH_Check(t *testing.T) {
+	server := newTestSSHServer(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/passwd": "root:x:0:0:root:/root:/bin/bash\ntoor:x:0:0::/root:/bin/sh\n",
+		"etc/group":  "root:x:0:\n",
+	})
+	target := server.dial(t, SSHConfig{})
+	target.Root = root
+
+	controls := fmt.Sprintf(`---
+controls:
+id: 1
+text: "Remote"
+groups:
+- id: 1.1
+  text: "Remote checks"
+  checks:
+    - id: 1.1.1
+      text: "Shell audits run on the remote host"
+      audit: "echo $BENCH_SSH_SERVER"
+      tests:
+        test_items:
+        - flag: "remote"
+      scored: true
+    - id: 1.1.2
+      text: "Exec audits run on the remote host"
+      audittype: "exec"
+      audit:
+        args: ["sh", "-c", "echo \"$GREETING $(pwd -P)\""]
+        env:
+          GREETING: "it's"
+        dir: %q
+      tests:
+        test_items:
+        - flag: "it's %s"
+      scored: true
+    - id: 1.1.3
+      text: "Native audits read files over SFTP"
+      audittype: "accounts"
+      tests:
+        test_items:
+        - path: "{.uid0_users}"
+          compare:
+            op: eq
+            value: "[\"root\",\"toor\"]"
+      scored: true
+`, root, mustEvalSymlinks(t, root))
+
+	c, err := NewBench().NewControls([]byte(controls), nil, target)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+	assert.Equal(t, &TargetMetadata{
+		Type:    TargetSSH,
+		Root:    root,
+		Host:    server.addr,
+		User:    "bench",
+		HostKey: ssh.FingerprintSHA256(server.hostKey.PublicKey()),
+	}, c.Target)
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 3}, summary)
+}
+
+func TestDialSSH_Agent(t *testing.T) {
+	server := newTestSSHServer(t)
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: server.clientKey}); err != nil {
+		t.Fatalf("failed to add key to agent: %v", err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn) // nolint: errcheck
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	target := server.dial(t, SSHConfig{Agent: true})
+	out, errMsg, _ := Audit("echo $BENCH_SSH_SERVER").Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Equal(t, "remote\n", out)
+}
+
+func TestDialSSH_Errors(t *testing.T) {
+	server := newTestSSHServer(t)
+	dir := t.TempDir()
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherPub, err := ssh.NewPublicKey(otherKey)
+	if err != nil {
+		t.Fatalf("failed to create public key: %v", err)
+	}
+	mismatch := filepath.Join(dir, "mismatch")
+	writeFiles(t, dir, map[string]string{
+		"empty":    "",
+		"mismatch": knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, otherPub) + "\n",
+	})
+
+	cases := []struct {
+		name    string
+		config  SSHConfig
+		wantErr string
+	}{
+		{
+			name:    "unknown host",
+			config:  SSHConfig{KeyFiles: []string{server.keyFile}, KnownHosts: []string{filepath.Join(dir, "empty")}},
+			wantErr: "is not in known_hosts",
+		},
+		{
+			name:    "host key mismatch",
+			config:  SSHConfig{KeyFiles: []string{server.keyFile}, KnownHosts: []string{mismatch}},
+			wantErr: "key mismatch",
+		},
+		{
+			name:    "no auth",
+			config:  SSHConfig{KnownHosts: []string{server.knownHosts}},
+			wantErr: "no SSH key or agent",
+		},
+		{
+			name:    "missing key",
+			config:  SSHConfig{KeyFiles: []string{filepath.Join(dir, "missing")}, KnownHosts: []string{server.knownHosts}},
+			wantErr: "failed to read SSH key",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.Address = server.addr
+			c.config.User = "bench"
+			_, err := DialSSH(c.config)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), c.wantErr)
+			}
+		})
+	}
+}
+
+func TestDialSSH_MaxSessions(t *testing.T) {
+	server := newTestSSHServer(t)
+	target := server.dial(t, SSHConfig{MaxSessions: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errMsg, _ := Audit("sleep 0.1").Execute(target)
+			assert.Empty(t, errMsg)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.maxActive))
+}
+
+func TestSSHTarget_Commands(t *testing.T) {
+	server := newTestSSHServer(t)
+	target := server.dial(t, SSHConfig{})
+
+	result, errMsg, _ := Audit("echo out; echo err >&2; exit 3").ExecuteResult(target)
+	assert.Contains(t, errMsg, "exit status 3")
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, 3, result.ExitCode)
+
+	result, _, _ = Audit("no-such-command").ExecuteResult(target)
+	assert.True(t, result.CommandNotFound)
+
+	result, _, _ = (&ExecAudit{Args: []string{"no-such-command"}}).ExecuteResult(target)
+	assert.True(t, result.CommandNotFound)
+
+	out, errMsg, state := (&ExecAudit{Args: []string{"/usr/bin/env"}, Env: map[string]string{"ONLY": "1"}, ClearEnv: true}).Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Equal(t, "ONLY=1\n", out)
+
+	_, errMsg, state = (&ExecAudit{Args: []string{"sleep", "5"}, Timeout: "100ms"}).Execute(target)
+	assert.Contains(t, errMsg, "timed out")
+	assert.EqualValues(t, WARN, state)
+}
+
+func TestSSHTarget_FSWalk(t *testing.T) {
+	server := newTestSSHServer(t)
+	target := server.dial(t, SSHConfig{})
+	target.Root = writeFSWalkTree(t)
+
+	out, errMsg, state := (&FSWalkAudit{Exclude: []string{"/var/cache"}}).Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	matches := map[string]FSWalkMatch{}
+	for _, m := range parseRows[FSWalkMatch](t, out) {
+		matches[m.Path] = m
+	}
+	assert.Len(t, matches, 4)
+	assert.Equal(t, []string{predicateSetuid}, matches["/usr/bin/passwd"].Predicates)
+	assert.Equal(t, []string{predicateWorldWritable}, matches["/srv/data/shared.txt"].Predicates)
+	assert.Equal(t, []string{predicateStickyMissing}, matches["/srv/tmp"].Predicates)
+	assert.Equal(t, uint32(os.Getuid()), matches["/srv/tmp"].UID)
+}
+
+func TestSSHTarget_Sockets(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("the test tables were taken from a little endian host")
+	}
+	server := newTestSSHServer(t)
+	target := server.dial(t, SSHConfig{})
+
+	// The byte order is the one of the remote host, found with uname
+	out, errMsg, state := (&SocketsAudit{Protocols: []string{"tcp"}, ProcRoot: writeTestProcNet(t)}).Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	listeners := parseRows[Listener](t, out)
+	if assert.Len(t, listeners, 2) {
+		assert.Equal(t, "127.0.0.1", listeners[0].Address)
+	}
+}
+
+func TestSSHTarget_Dial(t *testing.T) {
+	server := newTestSSHServer(t)
+	target := server.dial(t, SSHConfig{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"forwarded":true}`)) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	out, errMsg, state := (&HTTPAudit{URL: srv.URL}).Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Contains(t, out, `"forwarded":true`)
+}
+
+func TestRemoteCommand(t *testing.T) {
+	cases := []struct {
+		args []string
+		cmd  targetCommand
+		want string
+	}{
+		{args: []string{"/bin/sh"}, want: "/bin/sh"},
+		{args: []string{"printf", "%s\n", "it's a $(value)"}, want: `printf '%s` + "\n" + `' 'it'\''s a $(value)'`},
+		{args: []string{"echo", ""}, want: "echo ''"},
+		{
+			args: []string{"pwd"},
+			cmd:  targetCommand{dir: "/etc/my app", env: []string{"A=1", "B=x y"}, clearEnv: true},
+			want: `cd '/etc/my app' && env -i A=1 'B=x y' pwd`,
+		},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, remoteCommand(c.args, c.cmd))
+	}
+}
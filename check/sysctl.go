@@ -0,0 +1,174 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TypeSysctl audits kernel parameters.
+const TypeSysctl = "sysctl"
+
+// SysctlAudit reads kernel parameters from /proc/sys and the persistent
+// configuration from /etc/sysctl.conf and /etc/sysctl.d/*.conf.
+type SysctlAudit struct {
+	Keys     []string `yaml:"keys"`
+	ProcRoot string   `yaml:"proc_root"`
+	EtcRoot  string   `yaml:"etc_root"`
+}
+
+// SysctlParam is the state of a single kernel parameter.
+type SysctlParam struct {
+	Key            string `json:"key"`
+	Value          string `json:"value"`
+	Running        bool   `json:"running"`
+	PersistedValue string `json:"persisted_value"`
+	PersistedFile  string `json:"persisted_file"`
+	Persisted      bool   `json:"persisted"`
+	Match          bool   `json:"match"`
+}
+
+type sysctlSetting struct {
+	value string
+	file  string
+}
+
+// Execute reads each of the configured keys and returns one row per key.
+func (s *SysctlAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	if len(s.Keys) == 0 {
+		return auditFailed(fmt.Errorf("sysctl audit requires at least one key"))
+	}
+
+	target := targetFrom(customConfig)
+	persisted, err := readSysctlConfig(target.fs(), target.path(rootOrDefault(s.EtcRoot, "/etc")))
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	procRoot := target.path(rootOrDefault(s.ProcRoot, "/proc"))
+	params := make([]SysctlParam, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		p := SysctlParam{Key: normalizeSysctlKey(key)}
+
+		b, err := target.fs().ReadFile(filepath.Join(procRoot, "sys", sysctlKeyPath(key)))
+		if err == nil {
+			p.Value = normalizeSysctlValue(string(b))
+			p.Running = true
+		} else if !os.IsNotExist(err) {
+			return auditFailed(fmt.Errorf("failed to read kernel parameter %s: %v", key, err))
+		}
+
+		if setting, ok := persisted[p.Key]; ok {
+			p.PersistedValue = setting.value
+			p.PersistedFile = setting.file
+			p.Persisted = true
+		}
+		p.Match = p.Running && p.Persisted && p.Value == p.PersistedValue
+
+		params = append(params, p)
+	}
+
+	return jsonLinesResult(params)
+}
+
+// readSysctlConfig returns the effective persistent configuration. Files in
+// sysctl.d are applied in lexical order of their names, and sysctl.conf is
+// applied last, so later assignments override earlier ones.
+func readSysctlConfig(fsys targetFS, etcRoot string) (map[string]sysctlSetting, error) {
+	files, err := fsys.Glob(filepath.Join(etcRoot, "sysctl.d", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.Base(files[i]) < filepath.Base(files[j])
+	})
+	files = append(files, filepath.Join(etcRoot, "sysctl.conf"))
+
+	settings := map[string]sysctlSetting{}
+	for _, file := range files {
+		data, err := fsys.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		for key, value := range parseSysctlConfig(data) {
+			settings[key] = sysctlSetting{value: value, file: file}
+		}
+	}
+	return settings, nil
+}
+
+// parseSysctlConfig parses the "key = value" assignments of a sysctl.conf file.
+func parseSysctlConfig(data []byte) map[string]string {
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// A leading '-' only means errors setting the key are ignored
+		line = strings.TrimPrefix(line, "-")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		settings[normalizeSysctlKey(key)] = normalizeSysctlValue(value)
+	}
+	return settings
+}
+
+// normalizeSysctlKey returns the dotted form of a key. Like sysctl(8), a key
+// whose first separator is '/' uses '/' as the separator and may contain dots,
+// e.g. net/ipv4/conf/eth0.100/rp_filter.
+func normalizeSysctlKey(key string) string {
+	key = strings.Trim(strings.TrimSpace(key), "/")
+	if i := strings.IndexAny(key, "./"); i >= 0 && key[i] == '/' {
+		return swapSysctlSeparators(key)
+	}
+	return key
+}
+
+// sysctlKeyPath returns the path of a key relative to /proc/sys.
+func sysctlKeyPath(key string) string {
+	return swapSysctlSeparators(normalizeSysctlKey(key))
+}
+
+func swapSysctlSeparators(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		}
+		return r
+	}, key)
+}
+
+// normalizeSysctlValue collapses whitespace, so multi-value parameters read from
+// /proc compare equal to the same values written in a configuration file.
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,112 @@
+package check
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysctlAudit_Execute(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/sys/net/ipv4/ip_forward":              "0\n",
+		"proc/sys/kernel/randomize_va_space":        "2\n",
+		"proc/sys/net/ipv4/tcp_rmem":                "4096\t131072\t6291456\n",
+		"proc/sys/net/ipv4/conf/eth0.100/rp_filter": "1\n",
+		"etc/sysctl.d/10-network.conf":              "net.ipv4.ip_forward = 1\nnet.ipv4.tcp_rmem = 4096 131072 6291456\n",
+		"etc/sysctl.d/99-hardening.conf":            "# hardening\n-net.ipv4.ip_forward=0\n; comment\nnet/ipv4/conf/eth0.100/rp_filter = 2\n",
+		"etc/sysctl.d/README":                       "kernel.randomize_va_space = 0\n",
+		"etc/sysctl.conf":                           "kernel.randomize_va_space=1\n",
+	})
+
+	audit := &SysctlAudit{
+		Keys: []string{
+			"net.ipv4.ip_forward",
+			"kernel/randomize_va_space",
+			"net.ipv4.tcp_rmem",
+			"net.ipv4.conf.eth0/100.rp_filter",
+			"net.ipv6.conf.all.forwarding",
+		},
+		ProcRoot: filepath.Join(root, "proc"),
+		EtcRoot:  filepath.Join(root, "etc"),
+	}
+
+	out, errMsg, state := audit.Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	params := parseRows[SysctlParam](t, out)
+
+	expected := []SysctlParam{
+		{Key: "net.ipv4.ip_forward", Value: "0", Running: true, PersistedValue: "0", PersistedFile: filepath.Join(root, "etc/sysctl.d/99-hardening.conf"), Persisted: true, Match: true},
+		{Key: "kernel.randomize_va_space", Value: "2", Running: true, PersistedValue: "1", PersistedFile: filepath.Join(root, "etc/sysctl.conf"), Persisted: true, Match: false},
+		{Key: "net.ipv4.tcp_rmem", Value: "4096 131072 6291456", Running: true, PersistedValue: "4096 131072 6291456", PersistedFile: filepath.Join(root, "etc/sysctl.d/10-network.conf"), Persisted: true, Match: true},
+		{Key: "net.ipv4.conf.eth0/100.rp_filter", Value: "1", Running: true, PersistedValue: "2", PersistedFile: filepath.Join(root, "etc/sysctl.d/99-hardening.conf"), Persisted: true, Match: false},
+		{Key: "net.ipv6.conf.all.forwarding"},
+	}
+	assert.Equal(t, expected, params)
+}
+
+func TestSysctlAudit_NoKeys(t *testing.T) {
+	_, errMsg, state := (&SysctlAudit{}).Execute()
+	if state != WARN || errMsg == "" {
+		t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+	}
+}
+
+func TestSysctlAudit_Check(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/sys/net/ipv4/ip_forward":       "0\n",
+		"proc/sys/kernel/randomize_va_space": "2\n",
+		"etc/sysctl.conf":                    "net.ipv4.ip_forward = 0\nkernel.randomize_va_space = 2\n",
+	})
+
+	controls := `---
+controls:
+id: 1
+text: "Kernel"
+groups:
+- id: 1.1
+  text: "Network parameters"
+  checks:
+    - id: 1.1.1
+      text: "Ensure IP forwarding is disabled"
+      audittype: "sysctl"
+      audit:
+        keys: ["net.ipv4.ip_forward", "kernel.randomize_va_space"]
+        proc_root: "` + filepath.Join(root, "proc") + `"
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.match}"
+          compare:
+            op: eq
+            value: true
+      scored: true
+    - id: 1.1.2
+      text: "Ensure address space layout randomization is enabled"
+      audittype: "sysctl"
+      audit:
+        keys: ["net.ipv4.ip_forward", "kernel.randomize_va_space"]
+        proc_root: "` + filepath.Join(root, "proc") + `"
+        etc_root: "` + filepath.Join(root, "etc") + `"
+      tests:
+        test_items:
+        - path: "{[?(@.key==\"kernel.randomize_va_space\")].value}"
+          compare:
+            op: eq
+            value: 2
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2}, summary)
+}
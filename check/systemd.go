@@ -0,0 +1,281 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TypeSystemdUnit audits systemd unit files.
+const TypeSystemdUnit = "systemd_unit"
+
+// systemdUnitPaths are the system unit search paths, from highest to lowest priority.
+var systemdUnitPaths = []string{
+	"/etc/systemd/system.control",
+	"/run/systemd/system.control",
+	"/run/systemd/transient",
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// SystemdUnitAudit resolves a unit file and its drop-ins from the unit search
+// paths, without a running systemd.
+type SystemdUnitAudit struct {
+	Unit string `yaml:"unit"`
+	// Directive, e.g. "ExecStart" or "Service.ExecStart", returns only the
+	// values of that directive, one per line, instead of the whole unit.
+	Directive string `yaml:"directive"`
+	Root      string `yaml:"root"`
+}
+
+// SystemdUnit is the effective configuration of a unit.
+type SystemdUnit struct {
+	Unit        string                         `json:"unit"`
+	Found       bool                           `json:"found"`
+	Masked      bool                           `json:"masked"`
+	Path        string                         `json:"path,omitempty"`
+	DropIns     []string                       `json:"drop_ins"`
+	Sections    map[string]map[string][]string `json:"sections"`
+	ExecStart   string                         `json:"exec_start,omitempty"`
+	Environment map[string]string              `json:"environment"`
+}
+
+// Execute returns the unit as a single document, or the values of Directive.
+func (s *SystemdUnitAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	if s.Unit == "" {
+		return auditFailed(fmt.Errorf("systemd_unit audit requires a unit"))
+	}
+
+	target := targetFrom(customConfig)
+	unit, err := loadSystemdUnit(target.fs(), target.path(rootOrDefault(s.Root, "/")), s.Unit)
+	if err != nil {
+		return auditFailed(err)
+	}
+
+	if s.Directive != "" {
+		return strings.Join(unit.directive(s.Directive), "\n"), "", ""
+	}
+	return jsonResult(unit)
+}
+
+// loadSystemdUnit finds the unit file with the highest priority and merges its
+// drop-ins into it. Drop-ins are applied in lexical order of their names, and
+// a drop-in masks drop-ins with the same name in lower priority paths.
+func loadSystemdUnit(fsys targetFS, root, name string) (*SystemdUnit, error) {
+	if filepath.Ext(name) == "" {
+		name += ".service"
+	}
+	unit := &SystemdUnit{
+		Unit:        name,
+		DropIns:     []string{},
+		Sections:    map[string]map[string][]string{},
+		Environment: map[string]string{},
+	}
+
+	// Instances of a template unit, e.g. getty@tty1.service, use the template
+	// when they have no unit file of their own.
+	names := []string{name}
+	if at := strings.Index(name, "@"); at >= 0 && !strings.HasPrefix(name[at:], "@.") {
+		names = append(names, name[:at+1]+filepath.Ext(name))
+	}
+
+	for _, n := range names {
+		for _, dir := range systemdUnitPaths {
+			path := filepath.Join(root, dir, n)
+			info, err := fsys.Lstat(path)
+			if err != nil {
+				continue
+			}
+
+			unit.Found = true
+			unit.Path = filepath.Join(dir, n)
+			if info.Mode()&os.ModeSymlink != 0 {
+				if target, err := fsys.Readlink(path); err == nil && target == os.DevNull {
+					unit.Masked = true
+					return unit, nil
+				}
+			}
+
+			// Units are often symlinks, whose absolute targets are paths
+			// under root
+			path, err = resolvePath(fsys, root, path, true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read unit %s: %v", unit.Path, err)
+			}
+			if path == filepath.Join(root, os.DevNull) {
+				unit.Masked = true
+				return unit, nil
+			}
+			data, err := fsys.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read unit %s: %v", unit.Path, err)
+			}
+			if len(bytes.TrimSpace(data)) == 0 {
+				unit.Masked = true
+				return unit, nil
+			}
+			unit.apply(data)
+			break
+		}
+		if unit.Found {
+			break
+		}
+	}
+	if !unit.Found {
+		return unit, nil
+	}
+
+	dropIns := map[string]string{}
+	for i := len(names) - 1; i >= 0; i-- {
+		for j := len(systemdUnitPaths) - 1; j >= 0; j-- {
+			dir := filepath.Join(systemdUnitPaths[j], names[i]+".d")
+			files, _ := fsys.Glob(filepath.Join(root, dir, "*.conf"))
+			for _, file := range files {
+				dropIns[filepath.Base(file)] = filepath.Join(dir, filepath.Base(file))
+			}
+		}
+	}
+	var dropInNames []string
+	for name := range dropIns {
+		dropInNames = append(dropInNames, name)
+	}
+	sort.Strings(dropInNames)
+
+	for _, name := range dropInNames {
+		path, err := resolvePath(fsys, root, filepath.Join(root, dropIns[name]), true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read drop-in %s: %v", dropIns[name], err)
+		}
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read drop-in %s: %v", dropIns[name], err)
+		}
+		unit.DropIns = append(unit.DropIns, dropIns[name])
+		unit.apply(data)
+	}
+
+	if execStart := unit.Sections["Service"]["ExecStart"]; len(execStart) > 0 {
+		unit.ExecStart = execStart[len(execStart)-1]
+	}
+	for _, env := range unit.Sections["Service"]["Environment"] {
+		for _, assignment := range splitQuoted(env) {
+			if k, v, found := strings.Cut(assignment, "="); found {
+				unit.Environment[k] = v
+			}
+		}
+	}
+	return unit, nil
+}
+
+// apply merges a unit file into the unit. Every assignment is appended to the
+// directive's values and an empty assignment resets them, e.g. "ExecStart=".
+// For directives taking a single value the last value is the effective one.
+func (u *SystemdUnit) apply(data []byte) {
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var line string
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" && (strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";")) {
+			continue
+		}
+
+		// A trailing backslash continues the line
+		if strings.HasSuffix(text, `\`) {
+			line += strings.TrimSuffix(text, `\`) + " "
+			continue
+		}
+		line += text
+		text, line = strings.TrimSpace(line), ""
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			section = text[1 : len(text)-1]
+			continue
+		}
+		key, value, found := strings.Cut(text, "=")
+		if !found || section == "" {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if u.Sections[section] == nil {
+			u.Sections[section] = map[string][]string{}
+		}
+		if value == "" {
+			u.Sections[section][key] = []string{}
+			continue
+		}
+		u.Sections[section][key] = append(u.Sections[section][key], value)
+	}
+}
+
+// directive returns the values of a directive, given as "Key" or "Section.Key".
+func (u *SystemdUnit) directive(name string) []string {
+	if section, key, found := strings.Cut(name, "."); found {
+		return u.Sections[section][key]
+	}
+
+	var sections []string
+	for section := range u.Sections {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	var values []string
+	for _, section := range sections {
+		values = append(values, u.Sections[section][name]...)
+	}
+	return values
+}
+
+// splitQuoted splits a value on whitespace, keeping double or single quoted
+// words together, e.g. `A=1 "B=2 3"`.
+func splitQuoted(s string) []string {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inWord = true
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
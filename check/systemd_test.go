@@ -0,0 +1,193 @@
+package check
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSystemdFiles = map[string]string{
+	"lib/systemd/system/docker.service": `[Unit]
+Description=Docker Application Container Engine
+After=network-online.target
+
+[Service]
+Type=notify
+# the default is not to use systemd for cgroups
+ExecStart=/usr/bin/dockerd -H fd:// \
+  --containerd=/run/containerd/containerd.sock
+Environment="HTTP_PROXY=http://proxy:3128" NO_PROXY=localhost
+LimitNOFILE=infinity
+`,
+	"lib/systemd/system/docker.service.d/10-limits.conf":     "[Service]\nLimitNOFILE=1048576\n",
+	"lib/systemd/system/docker.service.d/20-masked.conf":     "[Service]\nEnvironment=MASKED=true\n",
+	"etc/systemd/system/docker.service.d/20-masked.conf":     "[Service]\nEnvironment=\"DEBUG=1\"\n",
+	"etc/systemd/system/docker.service.d/override.conf":      "[Service]\nExecStart=\nExecStart=/usr/bin/dockerd --icc=false --userland-proxy=false\n",
+	"etc/systemd/system/docker.service.d/README":             "not a drop-in",
+	"usr/lib/systemd/system/getty@.service":                  "[Service]\nExecStart=-/sbin/agetty -o '-p -- \\\\u' --noclear %I $TERM\n",
+	"run/systemd/system/getty@tty1.service.d/autologin.conf": "[Service]\nEnvironment=AUTOLOGIN=root\n",
+	"etc/systemd/system/empty.service":                       "",
+	"lib/systemd/system/ssh.service":                         "[Service]\nExecStart=/usr/sbin/sshd -D\n",
+}
+
+func writeTestSystemdRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, testSystemdFiles)
+	if err := os.Symlink(os.DevNull, filepath.Join(root, "etc/systemd/system/telnet.socket")); err != nil {
+		t.Fatalf("failed to mask unit: %v", err)
+	}
+	// An alias, whose absolute target is under root rather than on the host
+	if err := os.Symlink("/lib/systemd/system/ssh.service", filepath.Join(root, "etc/systemd/system/sshd.service")); err != nil {
+		t.Fatalf("failed to alias unit: %v", err)
+	}
+	return root
+}
+
+func TestSystemdUnitAudit_Execute(t *testing.T) {
+	root := writeTestSystemdRoot(t)
+
+	out, errMsg, state := (&SystemdUnitAudit{Unit: "docker", Root: root}).Execute()
+	if errMsg != "" || state != "" {
+		t.Fatalf("unexpected failure: %s %s", state, errMsg)
+	}
+
+	var unit SystemdUnit
+	if err := json.Unmarshal([]byte(out), &unit); err != nil {
+		t.Fatalf("failed to unmarshal %q: %v", out, err)
+	}
+
+	assert.Equal(t, "docker.service", unit.Unit)
+	assert.True(t, unit.Found)
+	assert.False(t, unit.Masked)
+	assert.Equal(t, "/lib/systemd/system/docker.service", unit.Path)
+	assert.Equal(t, []string{
+		"/lib/systemd/system/docker.service.d/10-limits.conf",
+		"/etc/systemd/system/docker.service.d/20-masked.conf",
+		"/etc/systemd/system/docker.service.d/override.conf",
+	}, unit.DropIns)
+	assert.Equal(t, []string{"/usr/bin/dockerd --icc=false --userland-proxy=false"}, unit.Sections["Service"]["ExecStart"])
+	assert.Equal(t, []string{"infinity", "1048576"}, unit.Sections["Service"]["LimitNOFILE"])
+	assert.Equal(t, "/usr/bin/dockerd --icc=false --userland-proxy=false", unit.ExecStart)
+	assert.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128", "NO_PROXY": "localhost", "DEBUG": "1"}, unit.Environment)
+}
+
+func TestSystemdUnitAudit_Units(t *testing.T) {
+	root := writeTestSystemdRoot(t)
+
+	cases := []struct {
+		unit    string
+		found   bool
+		masked  bool
+		dropIns int
+		exec    string
+	}{
+		{unit: "getty@tty1.service", found: true, dropIns: 1, exec: "-/sbin/agetty -o '-p -- \\\\u' --noclear %I $TERM"},
+		{unit: "telnet.socket", found: true, masked: true},
+		{unit: "empty.service", found: true, masked: true},
+		{unit: "sshd.service", found: true, exec: "/usr/sbin/sshd -D"},
+		{unit: "missing.service"},
+	}
+
+	for _, c := range cases {
+		unit, err := loadSystemdUnit(localFS{}, root, c.unit)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.unit, err)
+			continue
+		}
+		assert.Equal(t, c.found, unit.Found, c.unit)
+		assert.Equal(t, c.masked, unit.Masked, c.unit)
+		assert.Len(t, unit.DropIns, c.dropIns, c.unit)
+		assert.Equal(t, c.exec, unit.ExecStart, c.unit)
+	}
+}
+
+func TestSystemdUnitAudit_Directive(t *testing.T) {
+	root := writeTestSystemdRoot(t)
+
+	cases := []struct {
+		directive string
+		want      string
+	}{
+		{directive: "ExecStart", want: "/usr/bin/dockerd --icc=false --userland-proxy=false"},
+		{directive: "Service.LimitNOFILE", want: "infinity\n1048576"},
+		{directive: "Unit.After", want: "network-online.target"},
+		{directive: "Install.WantedBy", want: ""},
+	}
+
+	for _, c := range cases {
+		out, errMsg, state := (&SystemdUnitAudit{Unit: "docker.service", Directive: c.directive, Root: root}).Execute()
+		if errMsg != "" || state != "" {
+			t.Fatalf("%s: unexpected failure: %s %s", c.directive, state, errMsg)
+		}
+		assert.Equal(t, c.want, out, c.directive)
+	}
+}
+
+func TestSplitQuoted(t *testing.T) {
+	assert.Equal(t, []string{"A=1", "B=2 3", "C='4'"}, splitQuoted(`A=1 "B=2 3"  C="'4'"`))
+	assert.Equal(t, []string{"A=1 2"}, splitQuoted(`'A=1 2'`))
+	assert.Nil(t, splitQuoted(" "))
+}
+
+func TestSystemdUnitAudit_Check(t *testing.T) {
+	root := writeTestSystemdRoot(t)
+
+	controls := `---
+controls:
+id: 2
+text: "Docker daemon configuration"
+groups:
+- id: 2.1
+  text: "Docker daemon"
+  checks:
+    - id: 2.1.1
+      text: "Ensure network traffic is restricted between containers on the default bridge"
+      audittype: "systemd_unit"
+      audit:
+        unit: docker.service
+        directive: ExecStart
+        root: "` + root + `"
+      tests:
+        test_items:
+        - flag: "--icc"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 2.1.2
+      text: "Ensure live restore is enabled"
+      audittype: "systemd_unit"
+      audit:
+        unit: docker.service
+        directive: ExecStart
+        root: "` + root + `"
+      tests:
+        test_items:
+        - flag: "--live-restore"
+      scored: true
+    - id: 2.1.3
+      text: "Ensure the docker daemon uses the proxy"
+      audittype: "systemd_unit"
+      audit:
+        unit: docker.service
+        root: "` + root + `"
+      tests:
+        test_items:
+        - path: "{.environment.HTTP_PROXY}"
+          compare:
+            op: eq
+            value: "http://proxy:3128"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 2, Fail: 1}, summary)
+}
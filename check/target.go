@@ -0,0 +1,254 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Target types
+const (
+	// TargetHost is the host the bench runs on, or its filesystem mounted
+	// under a root.
+	TargetHost = "host"
+	// TargetImage is a container image flattened into a root filesystem.
+	TargetImage = "image"
+	// TargetRootFS is the root filesystem of an image or a machine.
+	TargetRootFS = "rootfs"
+	// TargetSSH is a remote host audited over SSH.
+	TargetSSH = "ssh"
+)
+
+// runtimeAuditTypes read running processes, sockets or mounts, or connect to
+// services, which offline targets don't have.
+var runtimeAuditTypes = []AuditType{TypeSockets, TypeLSM, TypeDocker, TypeMount, TypeHTTP, TypeTLSProbe}
+
+// runtimeAuditer is implemented by the native audit types which read the
+// running system with some of their settings only.
+type runtimeAuditer interface {
+	Auditer
+	readsRuntime() bool
+}
+
+// Target is the system a benchmark runs against. Pass it to NewControls as a
+// custom config. Native audit types read their files under Root, so a bench
+// running in a container can audit the node's filesystem mounted at e.g.
+// /host without rewriting the paths of every audit.
+type Target struct {
+	// Type is the type of the target, TargetHost by default.
+	Type string
+	// Root is the root filesystem of the target, "/" by default.
+	Root string
+	// Image is the image or root filesystem of an offline target.
+	Image string
+	// ShellWrapper is prepended to the commands run by shell and exec
+	// audits, to run them in the target, e.g. ChrootWrapper(root).
+	ShellWrapper []string
+	// tempDir holds the flattened image, removed by Close
+	tempDir string
+	// ssh is the connection to the remote host of an SSH target
+	ssh *sshClient
+}
+
+// TargetMetadata describes the target of a run in the report.
+type TargetMetadata struct {
+	Type         string   `json:"type"`
+	Image        string   `json:"image,omitempty"`
+	Root         string   `json:"root,omitempty"`
+	ShellWrapper []string `json:"shell_wrapper,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	User         string   `json:"user,omitempty"`
+	HostKey      string   `json:"host_key,omitempty"`
+}
+
+// String describes the target in the console report.
+func (m *TargetMetadata) String() string {
+	s := m.Type
+	if m.Host != "" {
+		s += " " + m.User + "@" + m.Host
+		if m.HostKey != "" {
+			s += " (host key " + m.HostKey + ")"
+		}
+	}
+	if m.Image != "" {
+		return s + " " + m.Image
+	}
+	if m.Root != "" {
+		s += " root " + m.Root
+	}
+	if len(m.ShellWrapper) > 0 {
+		s += fmt.Sprintf(" (commands run with %s)", strings.Join(m.ShellWrapper, " "))
+	}
+	return s
+}
+
+// ChrootWrapper runs commands with root as their root directory.
+func ChrootWrapper(root string) []string {
+	return []string{"chroot", root}
+}
+
+// NsenterWrapper runs commands in the namespaces of the process pid, usually
+// 1 to run them in the host's namespaces from a container sharing its PID
+// namespace.
+func NsenterWrapper(pid int) []string {
+	return []string{"nsenter", "--target", strconv.Itoa(pid), "--mount", "--uts", "--ipc", "--net", "--pid", "--"}
+}
+
+// defaultTarget is the host the bench runs on.
+var defaultTarget = &Target{}
+
+// targetFrom returns the target passed as a custom config, or the host the
+// bench runs on.
+func targetFrom(customConfig []interface{}) *Target {
+	for _, config := range customConfig {
+		switch t := config.(type) {
+		case Target:
+			return &t
+		case *Target:
+			if t != nil {
+				return t
+			}
+		}
+	}
+	return defaultTarget
+}
+
+// path returns the path of p, an absolute path of the target, where the bench
+// can read it. Its symlinks are resolved relative to Root when it is read
+// through fs.
+func (t *Target) path(p string) string {
+	if t.Root == "" || t.Root == "/" || p == "" {
+		return p
+	}
+	return filepath.Join(t.Root, p)
+}
+
+// command returns the command to run args in the target.
+func (t *Target) command(args ...string) []string {
+	return append(append([]string{}, t.ShellWrapper...), args...)
+}
+
+// fs returns the filesystem of the target.
+func (t *Target) fs() targetFS {
+	if t.ssh != nil {
+		return t.ssh.fs()
+	}
+	if t.Root != "" && t.Root != "/" {
+		return rootFS{root: t.Root}
+	}
+	return localFS{}
+}
+
+// run runs c in the target, with the shell wrapper of the target. A command
+// which exits with a non zero exit code returns an *exitError.
+func (t *Target) run(ctx context.Context, c targetCommand) error {
+	args := t.command(c.args...)
+	if t.ssh != nil {
+		return t.ssh.run(ctx, args, c)
+	}
+	return runLocal(ctx, args, c)
+}
+
+// output runs args in the target, without the shell wrapper, and returns
+// their stdout. Native audit types run tools this way, with the paths of the
+// target.
+func (t *Target) output(args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	c := targetCommand{args: args, stdout: &stdout}
+	var err error
+	if t.ssh != nil {
+		err = t.ssh.run(context.Background(), args, c)
+	} else {
+		err = runLocal(context.Background(), args, c)
+	}
+	return stdout.Bytes(), err
+}
+
+// dial connects to address from the target, so sockets only listening on the
+// target can be audited.
+func (t *Target) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if t.ssh != nil {
+		return t.ssh.dial(ctx, network, address)
+	}
+	return dialLocal(ctx, network, address)
+}
+
+// offline tests if the target is an image or a root filesystem, rather than
+// a running system.
+func (t *Target) offline() bool {
+	return t.Type == TargetImage || t.Type == TargetRootFS
+}
+
+// applicable tests if auditer, an audit of auditType, can be carried out on
+// the target. Offline targets have no processes or sockets, and commands would
+// run on the host the bench runs on rather than the target, unless a shell
+// wrapper runs them in the target.
+func (t *Target) applicable(auditType AuditType, auditer Auditer) bool {
+	if !t.offline() {
+		return true
+	}
+	switch auditType {
+	case "", TypeAudit, TypeExec:
+		return len(t.ShellWrapper) > 0
+	}
+	for _, runtimeType := range runtimeAuditTypes {
+		if auditType == runtimeType {
+			return false
+		}
+	}
+	if a, ok := auditer.(runtimeAuditer); ok && a.readsRuntime() {
+		return false
+	}
+	return true
+}
+
+func (t *Target) metadata() *TargetMetadata {
+	m := &TargetMetadata{Type: orDefault(t.Type, TargetHost), Image: t.Image, Root: t.Root, ShellWrapper: t.ShellWrapper}
+	if t.ssh != nil {
+		m.Host, m.User, m.HostKey = t.ssh.host, t.ssh.user, t.ssh.hostKey
+	}
+	if t.tempDir != "" {
+		// The flattened image is removed after the run
+		m.Root = ""
+	}
+	return m
+}
+
+// Close removes the image flattened by OpenImage, or disconnects from the
+// host of an SSH target.
+func (t *Target) Close() error {
+	if t.ssh != nil {
+		return t.ssh.Close()
+	}
+	if t.tempDir == "" {
+		return nil
+	}
+	// Directories of the image may not be writable
+	filepath.WalkDir(t.tempDir, func(p string, d fs.DirEntry, err error) error { // nolint: errcheck
+		if err == nil && d.IsDir() {
+			os.Chmod(p, 0700) // nolint: errcheck
+		}
+		return nil
+	})
+	return os.RemoveAll(t.tempDir)
+}
@@ -0,0 +1,199 @@
+package check
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarget_Path(t *testing.T) {
+	cases := []struct {
+		root string
+		path string
+		want string
+	}{
+		{root: "", path: "/etc", want: "/etc"},
+		{root: "/", path: "/etc", want: "/etc"},
+		{root: "/host", path: "/etc", want: "/host/etc"},
+		{root: "/host/", path: "/proc", want: "/host/proc"},
+		{root: "/host", path: "/", want: "/host"},
+		{root: "/host", path: "", want: ""},
+	}
+	for _, c := range cases {
+		target := &Target{Root: c.root}
+		assert.Equal(t, c.want, target.path(c.path), "root %q path %q", c.root, c.path)
+	}
+}
+
+func TestTarget_FSResolvesSymlinksInRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/os-release":          "ID=target\n",
+		"usr/lib/os-release.d/id": "ID=lib\n",
+	})
+	links := map[string]string{
+		"etc/absolute":  "/usr/lib/os-release.d/id",
+		"etc/relative":  "../usr/lib/os-release.d/id",
+		"etc/escaping":  "../../../../../../etc/os-release",
+		"etc/lib":       "/usr/lib",
+		"etc/loop":      "/etc/loop",
+		"etc/hostname2": "/etc/hostname",
+	}
+	for name, link := range links {
+		if err := os.Symlink(link, filepath.Join(root, name)); err != nil {
+			t.Fatalf("failed to link %s: %v", name, err)
+		}
+	}
+	fsys := (&Target{Root: root}).fs()
+
+	for name, want := range map[string]string{
+		"/etc/absolute":               "ID=lib\n",
+		"/etc/relative":               "ID=lib\n",
+		"/etc/escaping":               "ID=target\n",
+		"/etc/lib/os-release.d/id":    "ID=lib\n",
+		"/etc/lib/../../etc/absolute": "ID=lib\n",
+	} {
+		data, err := fsys.ReadFile(filepath.Join(root, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, string(data), name)
+	}
+
+	// /etc/hostname of the host the bench runs on is not read
+	_, err := fsys.ReadFile(filepath.Join(root, "/etc/hostname2"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fsys.ReadFile(filepath.Join(root, "/etc/loop"))
+	assert.Error(t, err)
+
+	info, err := fsys.Lstat(filepath.Join(root, "/etc/lib"))
+	assert.NoError(t, err)
+	assert.True(t, info.Mode()&fs.ModeSymlink != 0)
+	entries, err := fsys.ReadDir(filepath.Join(root, "/etc/lib"))
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "os-release.d", entries[0].Name())
+	}
+	matches, err := fsys.Glob(filepath.Join(root, "/etc/lib/*/id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "usr/lib/os-release.d/id")}, matches)
+}
+
+func TestTargetFrom(t *testing.T) {
+	assert.Same(t, defaultTarget, targetFrom(nil))
+	assert.Same(t, defaultTarget, targetFrom([]interface{}{"audit", Substitutions{}}))
+	assert.Equal(t, "/host", targetFrom([]interface{}{Target{Root: "/host"}}).Root)
+
+	target := &Target{Root: "/host"}
+	assert.Same(t, target, targetFrom([]interface{}{Substitutions{}, target}))
+}
+
+func TestTarget_ShellWrapper(t *testing.T) {
+	target := &Target{ShellWrapper: []string{"env", "WRAPPED=1"}}
+
+	out, errMsg, state := Audit("echo $WRAPPED").Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Equal(t, "1\n", out)
+
+	audit := &ExecAudit{Args: []string{"sh", "-c", "echo $WRAPPED"}}
+	out, errMsg, state = audit.Execute(target)
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+	assert.Equal(t, "1\n", out)
+}
+
+func TestTarget_ShellWrapperCommandNotFound(t *testing.T) {
+	// env exits with 127 when the command does not exist, like chroot and
+	// nsenter
+	target := &Target{ShellWrapper: []string{"env"}}
+
+	res, _, state := (&ExecAudit{Args: []string{"bench-common-missing-command"}}).ExecuteResult(target)
+	assert.Empty(t, state)
+	assert.Equal(t, shellCommandNotFound, res.ExitCode)
+	assert.True(t, res.CommandNotFound)
+}
+
+func TestTarget_Applicable(t *testing.T) {
+	cases := []struct {
+		auditType AuditType
+		auditer   Auditer
+		want      bool
+	}{
+		{auditType: TypeAccounts, auditer: &AccountsAudit{}, want: true},
+		{auditType: TypeSockets, auditer: &SocketsAudit{}},
+		{auditType: TypeHTTP, auditer: &HTTPAudit{URL: "https://localhost:10250/healthz"}},
+		{auditType: TypeTLSProbe, auditer: &TLSProbeAudit{Address: "localhost:6443"}},
+		{auditType: TypeAuditRules, auditer: &AuditRulesAudit{}, want: true},
+		{auditType: TypeAuditRules, auditer: &AuditRulesAudit{Source: auditRulesFiles}, want: true},
+		{auditType: TypeAuditRules, auditer: &AuditRulesAudit{Source: auditRulesLoaded}},
+		{auditType: TypeAudit, auditer: Audit("echo")},
+	}
+	image := &Target{Type: TargetImage, Root: t.TempDir()}
+	wrapped := &Target{Type: TargetImage, Root: image.Root, ShellWrapper: ChrootWrapper(image.Root)}
+	for _, c := range cases {
+		assert.Equal(t, c.want, image.applicable(c.auditType, c.auditer), "%s %+v", c.auditType, c.auditer)
+		// Commands run in the image with a shell wrapper
+		assert.Equal(t, c.want || c.auditType == TypeAudit, wrapped.applicable(c.auditType, c.auditer), "%s %+v", c.auditType, c.auditer)
+		// Everything is applicable to the host
+		assert.True(t, defaultTarget.applicable(c.auditType, c.auditer), "%s %+v", c.auditType, c.auditer)
+	}
+}
+
+func TestTarget_Check(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/passwd":  "root:x:0:0:root:/root:/bin/bash\ntoor:x:0:0::/root:/bin/sh\n",
+		"etc/group":   "root:x:0:\n",
+		"etc/shadow":  "root:*:19000:0:99999:7:::\n",
+		"etc/gshadow": "root:*::\n",
+	})
+
+	controls := `---
+controls:
+id: 6
+text: "System Maintenance"
+groups:
+- id: 6.2
+  text: "User and Group Settings"
+  checks:
+    - id: 6.2.1
+      text: "Ensure the UID 0 accounts are known"
+      audittype: "accounts"
+      tests:
+        test_items:
+        - path: "{.uid0_users}"
+          compare:
+            op: eq
+            value: "[\"root\",\"toor\"]"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil, &Target{Root: root})
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+	assert.Equal(t, &TargetMetadata{Type: TargetHost, Root: root}, c.Target)
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 1}, summary)
+
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatalf("failed to encode controls: %v", err)
+	}
+	var report struct {
+		Target TargetMetadata `json:"target"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	assert.Equal(t, root, report.Target.Root)
+
+	c, err = NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+	assert.Nil(t, c.Target)
+}
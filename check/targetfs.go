@@ -0,0 +1,296 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// targetFS reads the files of a target. Native audit types read through it,
+// so they audit remote targets the same way as the host the bench runs on.
+type targetFS interface {
+	ReadFile(name string) ([]byte, error)
+	// ReadDir returns the entries of the directory sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+	Glob(pattern string) ([]string, error)
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// localFS is the filesystem of the host the bench runs on.
+type localFS struct{}
+
+func (localFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (localFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (localFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (localFS) Readlink(name string) (string, error)       { return os.Readlink(name) }
+func (localFS) Glob(pattern string) ([]string, error)      { return filepath.Glob(pattern) }
+func (localFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+// maxSymlinks is the number of symlinks followed to resolve a path, like the
+// limit of Linux.
+const maxSymlinks = 40
+
+// rootFS is the filesystem of a target under root on the host the bench runs
+// on, e.g. the node's filesystem mounted at /host. Symlinks are resolved
+// relative to root, so an absolute symlink of the target reads the file of
+// the target rather than the file of the host the bench runs on.
+type rootFS struct {
+	root string
+}
+
+func (r rootFS) ReadFile(name string) ([]byte, error) {
+	p, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+func (r rootFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(p)
+}
+
+func (r rootFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(p)
+}
+
+func (r rootFS) Readlink(name string) (string, error) {
+	p, err := r.resolve(name, false)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(p)
+}
+
+// Glob resolves the directory the pattern starts from. Matches are returned
+// under the resolved directory.
+func (r rootFS) Glob(pattern string) ([]string, error) {
+	dir, rest := pattern, ""
+	for hasMeta(dir) {
+		dir, rest = filepath.Dir(dir), filepath.Join(filepath.Base(dir), rest)
+	}
+	p, err := r.resolve(dir, true)
+	if err != nil {
+		// Like filepath.Glob, I/O errors are ignored
+		return nil, nil
+	}
+	return filepath.Glob(filepath.Join(p, rest))
+}
+
+func (r rootFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	p, err := r.resolve(root, true)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	return filepath.WalkDir(p, fn)
+}
+
+// resolve returns the path of name on the host the bench runs on, with its
+// symlinks resolved relative to root.
+func (r rootFS) resolve(name string, follow bool) (string, error) {
+	return resolvePath(localFS{}, r.root, name, follow)
+}
+
+// resolvePath resolves the symlinks of name, a path under root, relative to
+// root, like the target would resolve them. The last element of name is only
+// resolved if follow is set. Names outside root are returned as they are.
+func resolvePath(fsys targetFS, root, name string, follow bool) (string, error) {
+	rel, err := filepath.Rel(root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return name, nil
+	}
+
+	// resolved is the path in the target, which ".." never leaves
+	resolved := "/"
+	pending := strings.Split(filepath.ToSlash(rel), "/")
+	links := 0
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, elem)
+		if len(pending) == 0 && !follow {
+			resolved = next
+			continue
+		}
+		info, err := fsys.Lstat(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			// Missing elements are left for the caller to report
+			resolved = next
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", &fs.PathError{Op: "resolve", Path: name, Err: errors.New("too many levels of symbolic links")}
+		}
+		link, err := fsys.Readlink(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(link) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(link, "/"), pending...)
+	}
+	return filepath.Join(root, filepath.FromSlash(resolved)), nil
+}
+
+// hasMeta tests if a path contains any of the magic characters recognized by
+// filepath.Match.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// walkDir walks the tree at root like filepath.WalkDir, for filesystems
+// which only list directories.
+func walkDir(fsys targetFS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(fsys targetFS, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		// Report the unreadable directory a second time, like filepath.WalkDir
+		if err = fn(name, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDirEntry(fsys, filepath.Join(name, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// targetCommand is a command run in a target.
+type targetCommand struct {
+	args           []string
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	// env is added to the environment of the command, as KEY=VALUE
+	env      []string
+	clearEnv bool
+	dir      string
+}
+
+// exitError reports a command which ran, and exited with a non zero exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+// commandNotFoundError reports a command which does not exist in the target.
+type commandNotFoundError struct {
+	err error
+}
+
+func (e *commandNotFoundError) Error() string { return e.err.Error() }
+
+// envList returns env as KEY=VALUE pairs sorted by key, with apply applied to
+// the values.
+func envList(env map[string]string, apply func(string) string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k+"="+apply(env[k]))
+	}
+	return list
+}
+
+// runLocal runs args on the host the bench runs on.
+func runLocal(ctx context.Context, args []string, c targetCommand) error {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) // nolint: gosec - no shell is involved
+	if errors.Is(cmd.Err, exec.ErrNotFound) || errors.Is(cmd.Err, fs.ErrNotExist) {
+		return &commandNotFoundError{err: cmd.Err}
+	}
+	cmd.Dir = c.dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = c.stdin, c.stdout, c.stderr
+	if c.clearEnv || len(c.env) > 0 {
+		cmd.Env = []string{}
+		if !c.clearEnv {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, c.env...)
+	}
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitError{code: exitErr.ExitCode(), err: err}
+	}
+	return err
+}
+
+// dialLocal connects to address from the host the bench runs on.
+func dialLocal(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, address)
+}
@@ -0,0 +1,224 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TypeTLSProbe audits the TLS configuration served by a listener.
+const TypeTLSProbe = "tls_probe"
+
+const defaultTLSProbeTimeout = 5 * time.Second
+
+var tlsProbeVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// TLSProbeAudit connects to a listener with each protocol version and cipher
+// suite, to find out what it actually serves. The cipher suites of TLS 1.3
+// can't be chosen by the client, so only the negotiated one is reported. Only
+// the cipher suites Go implements can be offered, so the others a listener
+// accepts, e.g. export or RC4-MD5 ones, are not reported.
+type TLSProbeAudit struct {
+	Address    string `yaml:"address"`
+	ServerName string `yaml:"server_name"`
+	Timeout    string `yaml:"timeout"`
+}
+
+// TLSProbe is what a listener accepted.
+type TLSProbe struct {
+	Address      string           `json:"address"`
+	Versions     []string         `json:"versions"`
+	MinVersion   string           `json:"min_version,omitempty"`
+	MaxVersion   string           `json:"max_version,omitempty"`
+	CipherSuites []string         `json:"cipher_suites"`
+	Protocols    []TLSProtocol    `json:"protocols"`
+	Certificates []TLSCertificate `json:"certificates"`
+	// UnknownCipherSuites reports that the cipher suites Go doesn't
+	// implement were not probed.
+	UnknownCipherSuites string `json:"unknown_cipher_suites"`
+}
+
+// tlsUnknownCipherSuites is reported as the UnknownCipherSuites of every probe.
+const tlsUnknownCipherSuites = "not probed"
+
+// TLSProtocol is a protocol version accepted by a listener, with the cipher
+// suites accepted for it.
+type TLSProtocol struct {
+	Version      string   `json:"version"`
+	CipherSuites []string `json:"cipher_suites"`
+}
+
+// TLSCertificate is a certificate of the chain served by a listener.
+type TLSCertificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysUntilExpiry    int       `json:"days_until_expiry"`
+	DNSNames           []string  `json:"dns_names"`
+	IPAddresses        []string  `json:"ip_addresses"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm"`
+	PublicKeyBits      int       `json:"public_key_bits"`
+	IsCA               bool      `json:"is_ca"`
+}
+
+// Execute probes the listener and returns a single document.
+func (p *TLSProbeAudit) Execute(customConfig ...interface{}) (result string, errMessage string, state State) {
+	if p.Address == "" {
+		return auditFailed(fmt.Errorf("tls_probe audit requires an address"))
+	}
+	timeout := defaultTLSProbeTimeout
+	if p.Timeout != "" {
+		d, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return auditFailed(fmt.Errorf("invalid timeout %q: %v", p.Timeout, err))
+		}
+		timeout = d
+	}
+
+	probe := TLSProbe{
+		Address:             p.Address,
+		Versions:            []string{},
+		CipherSuites:        []string{},
+		Protocols:           []TLSProtocol{},
+		Certificates:        []TLSCertificate{},
+		UnknownCipherSuites: tlsUnknownCipherSuites,
+	}
+
+	target := targetFrom(customConfig)
+	var lastErr error
+	seen := map[string]bool{}
+	for _, version := range tlsProbeVersions {
+		cs, err := p.handshake(target, timeout, version, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		protocol := TLSProtocol{Version: tls.VersionName(version), CipherSuites: []string{}}
+		if version == tls.VersionTLS13 {
+			protocol.CipherSuites = append(protocol.CipherSuites, tls.CipherSuiteName(cs.CipherSuite))
+		} else {
+			for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+				if !supportsVersion(suite, version) {
+					continue
+				}
+				if _, err := p.handshake(target, timeout, version, []uint16{suite.ID}); err == nil {
+					protocol.CipherSuites = append(protocol.CipherSuites, suite.Name)
+				}
+			}
+		}
+
+		for _, name := range protocol.CipherSuites {
+			if !seen[name] {
+				seen[name] = true
+				probe.CipherSuites = append(probe.CipherSuites, name)
+			}
+		}
+		probe.Protocols = append(probe.Protocols, protocol)
+		probe.Versions = append(probe.Versions, protocol.Version)
+		// Report the chain served with the highest protocol version
+		probe.Certificates = certificatesInfo(cs.PeerCertificates)
+	}
+
+	if len(probe.Versions) == 0 {
+		return auditFailed(fmt.Errorf("failed to connect to %s with TLS: %v", p.Address, lastErr))
+	}
+	probe.MinVersion = probe.Versions[0]
+	probe.MaxVersion = probe.Versions[len(probe.Versions)-1]
+	return jsonResult(probe)
+}
+
+// handshake connects to the listener from the target.
+func (p *TLSProbeAudit) handshake(target *Target, timeout time.Duration, version uint16, suites []uint16) (tls.ConnectionState, error) {
+	serverName := p.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(p.Address)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := target.dial(ctx, "tcp", p.Address)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       suites,
+		InsecureSkipVerify: true, // nolint: gosec - the chain is reported, not trusted
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+func supportsVersion(suite *tls.CipherSuite, version uint16) bool {
+	for _, v := range suite.SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func certificatesInfo(certs []*x509.Certificate) []TLSCertificate {
+	infos := make([]TLSCertificate, 0, len(certs))
+	for _, cert := range certs {
+		info := TLSCertificate{
+			Subject:            cert.Subject.String(),
+			Issuer:             cert.Issuer.String(),
+			SerialNumber:       cert.SerialNumber.String(),
+			NotBefore:          cert.NotBefore,
+			NotAfter:           cert.NotAfter,
+			DaysUntilExpiry:    int(time.Until(cert.NotAfter).Hours() / 24),
+			DNSNames:           cert.DNSNames,
+			IPAddresses:        []string{},
+			SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+			PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+			IsCA:               cert.IsCA,
+		}
+		if info.DNSNames == nil {
+			info.DNSNames = []string{}
+		}
+		for _, ip := range cert.IPAddresses {
+			info.IPAddresses = append(info.IPAddresses, ip.String())
+		}
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			info.PublicKeyBits = key.N.BitLen()
+		case *ecdsa.PublicKey:
+			info.PublicKeyBits = key.Curve.Params().BitSize
+		case ed25519.PublicKey:
+			info.PublicKeyBits = 256
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
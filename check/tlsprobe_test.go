@@ -0,0 +1,150 @@
+package check
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTLSServer starts a server which only accepts TLS 1.2 with two cipher suites.
+func newTLSServer(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	// The probe's rejected handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestTLSProbeAudit_Execute(t *testing.T) {
+	address := newTLSServer(t)
+
+	audit := TLSProbeAudit{Address: address}
+	out, errMsg, state := audit.Execute()
+	assert.Empty(t, errMsg)
+	assert.Empty(t, state)
+
+	probe := parseRows[TLSProbe](t, out)[0]
+	assert.Equal(t, address, probe.Address)
+	assert.Equal(t, []string{"TLS 1.2"}, probe.Versions)
+	assert.Equal(t, "TLS 1.2", probe.MinVersion)
+	assert.Equal(t, "TLS 1.2", probe.MaxVersion)
+	assert.ElementsMatch(t, []string{
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	}, probe.CipherSuites)
+	assert.Equal(t, "not probed", probe.UnknownCipherSuites)
+	assert.Len(t, probe.Protocols, 1)
+
+	if assert.Len(t, probe.Certificates, 1) {
+		cert := probe.Certificates[0]
+		assert.Equal(t, "O=Acme Co", cert.Subject)
+		assert.Equal(t, "RSA", cert.PublicKeyAlgorithm)
+		assert.Equal(t, 2048, cert.PublicKeyBits)
+		assert.Contains(t, cert.IPAddresses, "127.0.0.1")
+		assert.Contains(t, cert.DNSNames, "example.com")
+	}
+}
+
+func TestTLSProbeAudit_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		audit TLSProbeAudit
+	}{
+		{name: "no address", audit: TLSProbeAudit{}},
+		{name: "invalid timeout", audit: TLSProbeAudit{Address: "127.0.0.1:1", Timeout: "soon"}},
+		{name: "nothing listening", audit: TLSProbeAudit{Address: "127.0.0.1:1", Timeout: "1s"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, errMsg, state := c.audit.Execute()
+			assert.Empty(t, out)
+			assert.NotEmpty(t, errMsg)
+			assert.EqualValues(t, WARN, state)
+		})
+	}
+}
+
+func TestTLSProbeAudit_Check(t *testing.T) {
+	address := newTLSServer(t)
+
+	controls := `---
+controls:
+id: 1
+text: "Control Plane Components"
+groups:
+- id: 1.2
+  text: "API Server"
+  checks:
+    - id: 1.2.1
+      text: "Ensure that the API Server only allows TLS 1.2 or later"
+      audittype: "tls_probe"
+      audit:
+        address: "` + address + `"
+      tests:
+        test_items:
+        - path: "{.versions}"
+          compare:
+            op: valid_elements
+            value: "TLS 1.2,TLS 1.3"
+      scored: true
+    - id: 1.2.2
+      text: "Ensure that the API Server only makes use of strong cipher suites"
+      audittype: "tls_probe"
+      audit:
+        address: "` + address + `"
+      tests:
+        test_items:
+        - path: "{.cipher_suites}"
+          compare:
+            op: valid_elements
+            value: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_AES_128_GCM_SHA256"
+      scored: true
+    - id: 1.2.3
+      text: "Ensure that the API Server certificate uses a key of at least 2048 bits"
+      audittype: "tls_probe"
+      audit:
+        address: "` + address + `"
+      tests:
+        test_items:
+        - path: "{.certificates[0].public_key_bits}"
+          compare:
+            op: gte
+            value: 2048
+      scored: true
+    - id: 1.2.4
+      text: "Ensure that the API Server does not accept TLS 1.1"
+      audittype: "tls_probe"
+      audit:
+        address: "` + address + `"
+      tests:
+        test_items:
+        - path: "{.min_version}"
+          compare:
+            op: eq
+            value: "TLS 1.1"
+      scored: true
+`
+	c, err := NewBench().NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, Summary{Pass: 3, Fail: 1}, summary)
+}
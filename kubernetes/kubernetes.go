@@ -0,0 +1,135 @@
+// Copyright © 2026 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kubernetes provides an audit type which reads resources from the
+// Kubernetes API, so checks don't need kubectl.
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/bench-common/check"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// TypeKubernetes audits resources of the Kubernetes API.
+const TypeKubernetes check.AuditType = "kubernetes"
+
+const defaultTimeout = 30 * time.Second
+
+// Audit lists or gets resources of a single group, version and resource. When
+// Name is set the resource is returned as a single document, otherwise each
+// resource is returned as a row, to be tested with use_multiple_values.
+type Audit struct {
+	Group         string `yaml:"group"`
+	Version       string `yaml:"version"`
+	Resource      string `yaml:"resource"`
+	Namespace     string `yaml:"namespace"`
+	Name          string `yaml:"name"`
+	LabelSelector string `yaml:"label_selector"`
+	FieldSelector string `yaml:"field_selector"`
+	client        dynamic.Interface
+}
+
+// Register registers the kubernetes audit type with b. All the audits of
+// this type use client.
+func Register(b check.Bench, client dynamic.Interface) error {
+	if client == nil {
+		return fmt.Errorf("kubernetes audit type requires a client")
+	}
+	return b.RegisterAuditType(TypeKubernetes, func() interface{} {
+		return &Audit{client: client}
+	})
+}
+
+// NewClient creates a client from the kubeconfig file. When kubeconfig is
+// empty, the in-cluster config is used if available, and otherwise the
+// default kubeconfig loading rules ($KUBECONFIG, ~/.kube/config).
+func NewClient(kubeconfig string) (dynamic.Interface, error) {
+	config, err := restConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %v", err)
+	}
+	return dynamic.NewForConfig(config)
+}
+
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, nil
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// EmitsRows tests if the resources are listed, each as a row, rather than a
+// single resource returned by name.
+func (a *Audit) EmitsRows() bool {
+	return a.Name == ""
+}
+
+// Execute returns the resources as JSON.
+func (a *Audit) Execute(customConfig ...interface{}) (result string, errMessage string, state check.State) {
+	if a.client == nil {
+		return "", "kubernetes audit type was registered without a client", check.WARN
+	}
+	if a.Version == "" || a.Resource == "" {
+		return "", "kubernetes audit requires a version and a resource", check.WARN
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	gvr := schema.GroupVersionResource{Group: a.Group, Version: a.Version, Resource: a.Resource}
+	resource := a.client.Resource(gvr).Namespace(a.Namespace)
+
+	if a.Name != "" {
+		obj, err := resource.Get(ctx, a.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Sprintf("failed to get %s %s: %v", gvr.String(), a.Name, err), check.WARN
+		}
+		b, err := json.Marshal(obj.Object)
+		if err != nil {
+			return "", err.Error(), check.WARN
+		}
+		return string(b), "", ""
+	}
+
+	list, err := resource.List(ctx, metav1.ListOptions{LabelSelector: a.LabelSelector, FieldSelector: a.FieldSelector})
+	if err != nil {
+		return "", fmt.Sprintf("failed to list %s: %v", gvr.String(), err), check.WARN
+	}
+
+	var sb strings.Builder
+	for _, item := range list.Items {
+		b, err := json.Marshal(item.Object)
+		if err != nil {
+			return "", err.Error(), check.WARN
+		}
+		sb.Write(b)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), "", ""
+}
@@ -0,0 +1,188 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/bench-common/check"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newPod(namespace, name string, labels map[string]interface{}, hostPID bool) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+			"labels":    labels,
+		},
+		"spec": map[string]interface{}{
+			"hostPID": hostPID,
+		},
+	}}
+}
+
+func newFakeClient() *dynamicfake.FakeDynamicClient {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "pods"}: "PodList",
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds,
+		newPod("kube-system", "kube-apiserver", map[string]interface{}{"component": "kube-apiserver"}, false),
+		newPod("kube-system", "node-exporter", map[string]interface{}{"app": "monitoring"}, true),
+		newPod("default", "web", map[string]interface{}{"app": "web"}, false),
+	)
+}
+
+func TestAudit_Execute(t *testing.T) {
+	client := newFakeClient()
+
+	cases := []struct {
+		name    string
+		audit   Audit
+		names   []string
+		wantErr bool
+	}{
+		{name: "all namespaces", audit: Audit{Version: "v1", Resource: "pods"}, names: []string{"web", "kube-apiserver", "node-exporter"}},
+		{name: "namespace", audit: Audit{Version: "v1", Resource: "pods", Namespace: "kube-system"}, names: []string{"kube-apiserver", "node-exporter"}},
+		{name: "label selector", audit: Audit{Version: "v1", Resource: "pods", LabelSelector: "component=kube-apiserver"}, names: []string{"kube-apiserver"}},
+		{name: "get", audit: Audit{Version: "v1", Resource: "pods", Namespace: "default", Name: "web"}, names: []string{"web"}},
+		{name: "get missing", audit: Audit{Version: "v1", Resource: "pods", Namespace: "default", Name: "missing"}, wantErr: true},
+		{name: "no resource", audit: Audit{Version: "v1"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.audit.client = client
+			out, errMsg, state := c.audit.Execute()
+			if c.wantErr {
+				if state != check.WARN || errMsg == "" {
+					t.Errorf("expected WARN with an error message, got %q %q", state, errMsg)
+				}
+				return
+			}
+			if errMsg != "" || state != "" {
+				t.Fatalf("unexpected failure: %s %s", state, errMsg)
+			}
+
+			var names []string
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				var obj unstructured.Unstructured
+				if err := json.Unmarshal([]byte(line), &obj.Object); err != nil {
+					t.Fatalf("failed to unmarshal %q: %v", line, err)
+				}
+				names = append(names, obj.GetName())
+			}
+			assert.ElementsMatch(t, c.names, names)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(check.NewBench(), nil); err == nil {
+		t.Errorf("expected Register to fail without a client")
+	}
+
+	b := check.NewBench()
+	if err := Register(b, newFakeClient()); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	controls := `---
+controls:
+id: 5
+text: "Kubernetes Policies"
+groups:
+- id: 5.2
+  text: "Pod Security Standards"
+  checks:
+    - id: 5.2.1
+      text: "Minimize the admission of containers wishing to share the host process ID namespace"
+      audittype: "kubernetes"
+      audit:
+        version: v1
+        resource: pods
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.spec.hostPID}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.2.2
+      text: "Ensure the API server pod does not share the host process ID namespace"
+      audittype: "kubernetes"
+      audit:
+        version: v1
+        resource: pods
+        namespace: kube-system
+        label_selector: component=kube-apiserver
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.spec.hostPID}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.2.3
+      text: "Ensure the API server pod is running"
+      audittype: "kubernetes"
+      audit:
+        version: v1
+        resource: pods
+        namespace: kube-system
+        label_selector: component=kube-apiserver
+      tests:
+        test_items:
+        - path: "{[*].metadata.name}"
+          compare:
+            op: eq
+            value: kube-apiserver
+      scored: true
+    - id: 5.2.4
+      text: "Ensure the API server pod does not share the host process ID namespace"
+      audittype: "kubernetes"
+      audit:
+        version: v1
+        resource: pods
+        namespace: kube-system
+        name: kube-apiserver
+      tests:
+        test_items:
+        - path: "{.spec.hostPID}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+    - id: 5.2.5
+      text: "Minimize the admission of privileged pods in an empty namespace"
+      audittype: "kubernetes"
+      audit:
+        version: v1
+        resource: pods
+        namespace: empty
+      use_multiple_values: true
+      tests:
+        test_items:
+        - path: "{.spec.hostPID}"
+          compare:
+            op: eq
+            value: false
+      scored: true
+`
+	c, err := b.NewControls([]byte(controls), nil)
+	if err != nil {
+		t.Fatalf("could not create control object: %s", err)
+	}
+
+	summary := c.RunGroup()
+	assert.Equal(t, check.Summary{Pass: 4, Fail: 1}, summary)
+}
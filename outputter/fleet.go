@@ -0,0 +1,35 @@
+package outputter
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/bench-common/check"
+	"github.com/aquasecurity/bench-common/util"
+)
+
+// OutputFleet outputs the report of a fleet run in the format of config.
+func OutputFleet(report *check.FleetReport, config *Config) error {
+	return outputFleet(report, config, newFile(config.Filename))
+}
+
+func outputFleet(report *check.FleetReport, config *Config, fileHandler fileHandler) error {
+	var out []byte
+	var err error
+	switch config.Format {
+	case JSONFormat:
+		out, err = report.JSON()
+	case JUnitFormat:
+		out, err = report.JUnit()
+	default:
+		util.PrettyPrintFleet(report, config.Console.NoRemediations, config.Console.IncludeTestOutput)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fleet report - %v", err)
+	}
+
+	if err := fileHandler.Handle(string(out)); err != nil {
+		return fmt.Errorf("fleet report - error Writing data: %v", err)
+	}
+	return nil
+}
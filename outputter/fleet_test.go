@@ -0,0 +1,44 @@
+package outputter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/bench-common/check"
+)
+
+type recordingFile struct {
+	data string
+}
+
+func (rf *recordingFile) Handle(data string) error {
+	rf.data = data
+	return nil
+}
+
+func TestOutputFleet(t *testing.T) {
+	report := &check.FleetReport{
+		Hosts: []*check.FleetHostResult{{Name: "node-1", Error: "connection refused"}},
+	}
+
+	cases := []struct {
+		format Format
+		want   string
+	}{
+		{format: JSONFormat, want: `"error":"connection refused"`},
+		{format: JUnitFormat, want: "<testsuites>"},
+	}
+	for _, c := range cases {
+		rf := &recordingFile{}
+		if err := outputFleet(report, &Config{Format: c.format}, rf); err != nil {
+			t.Fatalf("outputFleet failed: %v", err)
+		}
+		if !strings.Contains(rf.data, c.want) {
+			t.Errorf("expected %q in %q", c.want, rf.data)
+		}
+	}
+
+	if err := outputFleet(report, &Config{Format: JSONFormat}, &mockFile{fail: true}); err == nil {
+		t.Errorf("expected an error when the data can't be written")
+	}
+}
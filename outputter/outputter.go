@@ -40,7 +40,7 @@ type Config struct {
 
 // BuildOutputter builds a new outputter
 func BuildOutputter(summary check.Summary, config *Config) Outputter {
-	if summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 {
+	if summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.NotApplicable > 0 {
 		switch config.Format {
 		case JSONFormat:
 			return NewJSON(config.Filename)
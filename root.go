@@ -18,6 +18,16 @@ var (
 	outputFile        string
 	define            []string
 	substitutionFile  string
+	hostRoot          string
+	hostExec          string
+	image             string
+	sshDest           string
+	sshKeys           []string
+	sshAgent          bool
+	knownHosts        []string
+	sshMaxSessions    int
+	fleetFile         string
+	detect            bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -51,6 +61,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.app.yaml)")
 	rootCmd.PersistentFlags().StringVar(&substitutionFile, "substitution", "", "parameters substitution file")
 	rootCmd.PersistentFlags().StringArrayVar(&define, "define", []string{""}, "")
+	rootCmd.PersistentFlags().BoolVar(&detect, "detect", false, "Detect constraints, e.g. platform or kernel, from the target. --define overrides detected values")
+	rootCmd.PersistentFlags().StringVar(&hostRoot, "host-root", "", "Root of the host filesystem, e.g. /host when it is mounted in a container")
+	rootCmd.PersistentFlags().StringVar(&image, "image", "", "Audit an OCI image layout, a docker save tarball or a root filesystem directory offline")
+	rootCmd.PersistentFlags().StringVar(&hostExec, "host-exec", "", "Run shell audits in the host with chroot (into --host-root) or nsenter")
+	rootCmd.PersistentFlags().StringVar(&sshDest, "ssh", "", "Audit a remote host over SSH, as [user@]host[:port]")
+	rootCmd.PersistentFlags().StringArrayVar(&sshKeys, "ssh-key", nil, "Private key to authenticate with over SSH (default is the SSH agent, or ~/.ssh/id_*)")
+	rootCmd.PersistentFlags().BoolVar(&sshAgent, "ssh-agent", false, "Authenticate with the SSH agent at SSH_AUTH_SOCK, in addition to --ssh-key")
+	rootCmd.PersistentFlags().StringArrayVar(&knownHosts, "known-hosts", nil, "known_hosts file verifying the SSH host key (default is ~/.ssh/known_hosts)")
+	rootCmd.PersistentFlags().IntVar(&sshMaxSessions, "ssh-max-sessions", 4, "Maximum number of commands run at once on the SSH host")
+	rootCmd.PersistentFlags().StringVar(&fleetFile, "fleet", "", "Run the controls against each host listed in a fleet file, and merge their results")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -23,16 +23,18 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 	"os"
+	"sort"
 	"strings"
 )
 
 var (
 	// Print colors
 	colors = map[check.State]*color.Color{
-		check.PASS: color.New(color.FgGreen),
-		check.FAIL: color.New(color.FgRed),
-		check.WARN: color.New(color.FgYellow),
-		check.INFO: color.New(color.FgBlue),
+		check.PASS:          color.New(color.FgGreen),
+		check.FAIL:          color.New(color.FgRed),
+		check.WARN:          color.New(color.FgYellow),
+		check.INFO:          color.New(color.FgBlue),
+		check.NOTAPPLICABLE: color.New(color.FgHiBlack),
 	}
 )
 
@@ -82,50 +84,130 @@ func colorPrint(state check.State, s string) {
 // PrettyPrint outputs the results to stdout in human-readable format
 func PrettyPrint(r *check.Controls, summary check.Summary, noRemediations, includeTestOutput bool) {
 	colorPrint(check.INFO, fmt.Sprintf("%s %s\n", r.ID, r.Description))
-	for _, g := range r.Groups {
-		colorPrint(check.INFO, fmt.Sprintf("%s %s\n", g.ID, g.Description))
+	if r.Target != nil {
+		colorPrint(check.INFO, fmt.Sprintf("Target: %s\n", r.Target))
+	}
+	if len(r.Detected) > 0 {
+		colorPrint(check.INFO, fmt.Sprintf("Detected: %s\n", formatConstraints(r.Detected)))
+	}
+	if r.Reason != "" {
+		colorPrint(check.INFO, fmt.Sprintf("%s\n", r.Reason))
+	}
+	check.WalkGroups(r.Groups, func(g *check.Group, ancestors []*check.Group) {
+		// Sub groups are indented below their parent
+		indent := strings.Repeat("  ", len(ancestors))
+		colorPrint(check.INFO, fmt.Sprintf("%s%s %s\n", indent, g.ID, g.Description))
 		for _, c := range g.Checks {
-			colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Description))
+			colorPrint(c.State, fmt.Sprintf("%s%s %s\n", indent, c.ID, c.Description))
 
 			if includeTestOutput && c.State == check.FAIL && len(c.ActualValue) > 0 {
 				printRawOutput(c.ActualValue)
 			}
+			if includeTestOutput && len(c.Selection) > 0 {
+				printRawOutput(strings.Join(c.Selection, "\n"))
+			}
 		}
-	}
+	})
 
 	fmt.Println()
 
 	// Print remediations.
 	if !noRemediations && (summary.Fail > 0 || summary.Warn > 0 || summary.Info > 0) {
 		colors[check.WARN].Printf("== Remediations ==\n")
-		for _, g := range r.Groups {
+		check.WalkGroups(r.Groups, func(g *check.Group, _ []*check.Group) {
 			for _, c := range g.Checks {
+				if c.State == check.NOTAPPLICABLE {
+					continue
+				}
 				if (c.State != check.PASS && c.Reason == "") || (c.Type == "manual") {
 					fmt.Printf("%s %s\n", c.ID, c.Remediation)
 				} else if c.State != check.PASS {
 					fmt.Printf("%s %s\n", c.ID, c.Reason)
 				}
 			}
-		}
+		})
 		fmt.Println()
 	}
 
 	// Print summary setting output color to highest severity.
-	var res check.State
+	colors[summaryState(summary)].Printf("== Summary ==\n")
+	printSummaryCounts(summary)
+}
+
+// PrettyPrintFleet outputs the results of each host of a fleet run, then the
+// hosts each check did not pass on, and the totals of the fleet.
+func PrettyPrintFleet(r *check.FleetReport, noRemediations, includeTestOutput bool) {
+	for _, host := range r.Hosts {
+		colorPrint(check.INFO, fmt.Sprintf("== Host %s ==\n", host.Name))
+		if host.Controls == nil {
+			colorPrint(check.WARN, fmt.Sprintf("Host could not be audited: %s\n\n", host.Error))
+			continue
+		}
+		PrettyPrint(host.Controls, host.Summary, noRemediations, includeTestOutput)
+		fmt.Println()
+	}
+
+	colors[check.INFO].Printf("== Fleet ==\n")
+	for _, c := range r.Checks {
+		for _, state := range []check.State{check.FAIL, check.WARN} {
+			if hosts := c.Hosts[state]; len(hosts) > 0 {
+				colorPrint(state, fmt.Sprintf("%s %s: %s\n", c.ID, c.Description, strings.Join(hosts, ", ")))
+			}
+		}
+	}
+	for _, host := range r.Hosts {
+		if host.Controls == nil {
+			colorPrint(check.WARN, fmt.Sprintf("%s could not be audited\n", host.Name))
+		}
+	}
+	fmt.Println()
+
+	res := summaryState(r.Summary)
+	if r.HostsUnreachable > 0 && res != check.FAIL {
+		res = check.WARN
+	}
+	colors[res].Printf("== Fleet Summary ==\n")
+	fmt.Printf("%d hosts, %d could not be audited, %d with failing checks\n",
+		r.HostsTotal, r.HostsUnreachable, r.HostsFailed,
+	)
+	printSummaryCounts(r.Summary)
+}
+
+// summaryState returns the highest severity of the checks summarized.
+func summaryState(summary check.Summary) check.State {
 	if summary.Fail > 0 {
-		res = check.FAIL
+		return check.FAIL
 	} else if summary.Warn > 0 {
-		res = check.WARN
+		return check.WARN
 	} else if summary.Info > 0 {
-		res = check.INFO
-	} else {
-		res = check.PASS
+		return check.INFO
 	}
+	return check.PASS
+}
 
-	colors[res].Printf("== Summary ==\n")
+func printSummaryCounts(summary check.Summary) {
 	fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n",
 		summary.Pass, summary.Fail, summary.Warn, summary.Info,
 	)
+	if summary.NotApplicable > 0 {
+		fmt.Printf("%d checks NOT_APPLICABLE\n", summary.NotApplicable)
+	}
+}
+
+// formatConstraints formats constraints as key=value pairs, sorted by key.
+func formatConstraints(constraints map[string][]string) string {
+	keys := make([]string, 0, len(constraints))
+	for key := range constraints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range constraints[key] {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, ", ")
 }
 
 // verifyBin checks that the binary specified is running